@@ -0,0 +1,249 @@
+// Command propctl inspects and validates properties configurations.
+// Configs are resolved like applications do, with the same flags:
+//
+//	propctl show --mode prod --config-dir resx
+//	propctl get app.plateform.baseurl --config-dir resx
+//	propctl validate --config-dir resx
+//	propctl modes --config-dir resx
+//	propctl diff staging prod --config-dir resx
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/heirko/go-contrib/properties"
+	"github.com/spf13/pflag"
+)
+
+const usage = `Usage: propctl <command> [flags]
+
+Commands:
+  show          print the effective merged config
+  get <key>     print a property value
+  validate      parse base and mode config files, report syntax errors
+  modes         list available modes
+  diff <a> <b>  print keys added, removed and changed by mode b compared to mode a
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run execute a command and return the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	flagSet := pflag.NewFlagSet("propctl", pflag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	flagSet.String("format", "", "Output format of show: json (default), yaml, toml or flat, of diff: text (default) or json")
+	flagSet.Bool("explain", false, "Print where the value of get comes from")
+	flagSet.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flagSet.PrintDefaults()
+	}
+
+	// same flags as properties.DefaultConfig, reading the current directory by default
+	c := properties.NewConfig()
+	c.Flags = []properties.Flag{
+		{Name: properties.ModeTag, Default: "", Usage: "Execution mode, or comma separated modes, e.g. 'prod,eu'"},
+		{Name: properties.ConfigDirTag, Default: ".", Usage: "Configuration directory"},
+		{Name: properties.ConfigNameTag, Default: properties.DefaultConfigName, Usage: "Configuration name without extension"},
+		{Name: properties.ConfigTypeTag, Default: properties.DefaultConfigType, Usage: "Configuration type, e.g.: json, yaml,..."},
+	}
+	c.FlagSet = flagSet
+	for _, flag := range c.Flags {
+		flagSet.String(flag.Name, flag.Default, flag.Usage)
+	}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		flagSet.Usage()
+		return 2
+	}
+	command := args[0]
+	c.Args = args[1:]
+
+	var err error
+	switch command {
+	case "show":
+		err = show(c, stdout)
+	case "get":
+		err = get(c, stdout)
+	case "validate":
+		err = validate(c, stdout)
+	case "modes":
+		err = modes(c, stdout)
+	case "diff":
+		err = diff(c, stdout)
+	default:
+		fmt.Fprintf(stderr, "propctl: unknown command %q\n", command)
+		flagSet.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "propctl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// load read base config then modes
+func load(c properties.Config) (*properties.Properties, error) {
+	props, err := properties.NewE(c)
+	if err != nil {
+		return nil, err
+	}
+	if err = props.LoadModeE(); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+func show(c properties.Config, w io.Writer) error {
+	props, err := load(c)
+	if err != nil {
+		return err
+	}
+	format, _ := c.FlagSet.GetString("format")
+	if format == "" {
+		format = properties.ExportJSON
+	}
+	return props.Export(w, format)
+}
+
+func get(c properties.Config, w io.Writer) error {
+	props, err := load(c)
+	if err != nil {
+		return err
+	}
+	args := c.FlagSet.Args()
+	if len(args) != 1 {
+		return fmt.Errorf("get expects one key, got %d", len(args))
+	}
+
+	explain, _ := c.FlagSet.GetBool("explain")
+	pv, ok := props.Explain(args[0])
+	if ok {
+		if explain {
+			writeExplain(w, pv)
+		} else if pv.Secret {
+			fmt.Fprintln(w, properties.Redacted)
+		} else {
+			fmt.Fprintln(w, pv.Value)
+		}
+		return nil
+	}
+
+	// a parent key, print each of its keys
+	prefix := strings.ToLower(args[0]) + "."
+	var found bool
+	for _, pv := range props.AllProvenance() {
+		if !strings.HasPrefix(pv.Key, prefix) {
+			continue
+		}
+		found = true
+		if explain {
+			writeExplain(w, pv)
+			continue
+		}
+		value := pv.Value
+		if pv.Secret {
+			value = properties.Redacted
+		}
+		fmt.Fprintf(w, "%s=%v\n", pv.Key, value)
+	}
+	if !found {
+		return fmt.Errorf("%s is not set", args[0])
+	}
+	return nil
+}
+
+// writeExplain print where a value comes from and the values it shadows
+func writeExplain(w io.Writer, pv properties.Provenance) {
+	fmt.Fprintln(w, pv)
+	for _, shadowed := range pv.Shadowed {
+		fmt.Fprintf(w, "  shadows %s\n", shadowed)
+	}
+}
+
+// resolve parse flags only, config files may be invalid,
+// and return the config dir, name and type apps would use
+func resolve(c properties.Config) (dir, name, configType string, err error) {
+	if err = c.FlagSet.Parse(c.Args); err != nil {
+		return "", "", "", err
+	}
+	dir, _ = c.FlagSet.GetString(properties.ConfigDirTag)
+	name, _ = c.FlagSet.GetString(properties.ConfigNameTag)
+	configType, _ = c.FlagSet.GetString(properties.ConfigTypeTag)
+	return dir, name, configType, nil
+}
+
+func validate(c properties.Config, w io.Writer) error {
+	dir, name, configType, err := resolve(c)
+	if err != nil {
+		return err
+	}
+	files, err := properties.FindConfigFiles([]string{dir}, name, configType)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no %s.%s config file in %s", name, configType, dir)
+	}
+
+	var invalid int
+	for _, file := range files {
+		if err := properties.CheckConfigFile(file.Path, configType); err != nil {
+			invalid++
+			fmt.Fprintf(w, "FAIL %v\n", err)
+			continue
+		}
+		fmt.Fprintf(w, "ok   %s\n", file.Path)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d config files are invalid", invalid, len(files))
+	}
+	return nil
+}
+
+func modes(c properties.Config, w io.Writer) error {
+	dir, name, configType, err := resolve(c)
+	if err != nil {
+		return err
+	}
+	files, err := properties.FindConfigFiles([]string{dir}, name, configType)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.Mode != "" {
+			fmt.Fprintf(w, "%s\t%s\n", file.Mode, file.Path)
+		}
+	}
+	return nil
+}
+
+func diff(c properties.Config, w io.Writer) error {
+	if err := c.FlagSet.Parse(c.Args); err != nil {
+		return err
+	}
+	args := c.FlagSet.Args()
+	if len(args) != 2 {
+		return fmt.Errorf("diff expects two modes, got %d", len(args))
+	}
+
+	d, err := properties.DiffModes(c, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	switch format, _ := c.FlagSet.GetString("format"); format {
+	case "", "text":
+		return d.WriteText(w)
+	case "json":
+		return d.WriteJSON(w)
+	default:
+		return fmt.Errorf("unsupported diff format %q", format)
+	}
+}
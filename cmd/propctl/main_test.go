@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const resx = "../../properties_test/resx"
+
+func propctl(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestShowAndGet(t *testing.T) {
+	code, out, _ := propctl("show", "--mode", "test,eu", "--config-dir", resx, "--format", "flat")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "app.plateform.baseurl=http://tapp.test.me\n")
+	assert.Contains(t, out, "mode=test,eu\n")
+
+	code, out, _ = propctl("get", "name", "--config-dir", resx, "--mode", "test")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "Cake\n", out)
+
+	code, out, _ = propctl("get", "app.plateform.baseurl", "--config-dir", resx, "--mode", "test", "--explain")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "(mode ")
+	assert.Contains(t, out, "shadows app.plateform.baseurl=http://tapp.me (file ")
+
+	code, out, _ = propctl("get", "app.plateform", "--config-dir", resx, "--mode", "test", "--explain")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "app.plateform.baseurl=http://tapp.test.me (mode ")
+	assert.NotContains(t, out, "(override)")
+
+	code, _, errOut := propctl("get", "unknown", "--config-dir", resx, "--mode", "test")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "unknown is not set")
+}
+
+func TestValidateAndModes(t *testing.T) {
+	code, out, errOut := propctl("validate", "--config-dir", resx)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, out, "ok   "+resx+"/app.json")
+	assert.Contains(t, out, "FAIL "+resx+"/testbuggy.app.json:6:")
+	assert.Contains(t, errOut, "1 of 4 config files are invalid")
+
+	code, out, _ = propctl("validate", "--config-dir", resx, "--config-name", "interpolation")
+	assert.Equal(t, 0, code)
+
+	code, out, _ = propctl("modes", "--config-dir", resx)
+	assert.Equal(t, 0, code)
+	var modes []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		modes = append(modes, strings.Fields(line)[0])
+	}
+	assert.Equal(t, []string{"eu", "test", "testbuggy"}, modes)
+
+	code, _, _ = propctl("unknown")
+	assert.Equal(t, 2, code)
+}
+
+func TestDiff(t *testing.T) {
+	code, out, _ := propctl("diff", "test", "eu", "--config-dir", resx)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "+ app.plateform.region=eu\n")
+
+	code, out, _ = propctl("diff", "test", "eu", "--config-dir", resx, "--format", "json")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, `"kind": "added"`)
+
+	code, _, errOut := propctl("diff", "test", "--config-dir", resx)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "expects two modes")
+}
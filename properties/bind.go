@@ -0,0 +1,175 @@
+package properties
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Struct tags read by Bind
+const (
+	// PropTag is the property key, e.g. `prop:"db.url"`
+	PropTag = "prop"
+	// EnvTag is the environment variable overriding the property, e.g. `env:"DB_URL"`
+	EnvTag = "env"
+	// FlagTag is the command line flag overriding the property, e.g. `flag:"db-url"`
+	FlagTag = "flag"
+	// DefaultTag is the property default value, e.g. `default:"localhost:28015"`
+	DefaultTag = "default"
+	// UsageTag is the flag usage shown in the help, e.g. `usage:"Database url"`
+	UsageTag = "usage"
+)
+
+// boundField is a struct field declared with a PropTag
+type boundField struct {
+	index []int
+	key   string
+	env   string
+	flag  string
+	dflt  string
+	usage string
+}
+
+// Bind declares flags, env bindings and defaults from target struct tags,
+// then loads config files and mode overlays like New and LoadModeProperties,
+// and fills target. target must be a pointer to a struct, e.g.:
+//
+//	type MyConfig struct {
+//		DbUrl string `prop:"db.url" env:"DB_URL" flag:"db-url" default:"localhost:28015" usage:"Database url"`
+//	}
+//	var c MyConfig
+//	props, err := properties.Bind(&c, properties.DefaultConfig())
+//
+// Fields without PropTag are filled like Unmarshal does.
+// Precedence is unchanged: flag, then env, then mode files, then base file, then default.
+func Bind(target interface{}, config ...Config) (*Properties, error) {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("properties: Bind target must be a pointer to a struct, got %T", target)
+	}
+
+	var c Config
+	if len(config) == 0 {
+		c = NewConfig()
+	} else {
+		c = config[0]
+	}
+
+	// flags are only bound to their property key, not to a key named after the flag like Config.Flags
+	fields := boundFields(value.Elem().Type(), nil)
+	flagSet := Properties{Config: c}.flagSet()
+	for _, field := range fields {
+		if field.flag != "" && flagSet.Lookup(field.flag) == nil {
+			flagSet.String(field.flag, field.dflt, field.usage)
+		}
+	}
+
+	props, err := NewE(c)
+	if err != nil {
+		return nil, err
+	}
+	// without Config.Flags, New does not parse the flag set
+	if !flagSet.Parsed() {
+		if err = flagSet.Parse(c.args()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range fields {
+		if field.flag != "" {
+			props.bindFlag(field.key, field.flag)
+		} else if field.dflt != "" {
+			props.SetDefault(field.key, field.dflt)
+		}
+		if field.env != "" {
+			props.bindEnv(field.key, field.env)
+		}
+	}
+
+	if err = props.LoadModeE(); err != nil {
+		return nil, err
+	}
+
+	if err = props.Unmarshal(target); err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		v := props.Get(field.key)
+		if v == nil {
+			continue
+		}
+		out := value.Elem().FieldByIndex(field.index).Addr().Interface()
+		if err = decode(v, out); err != nil {
+			return nil, fmt.Errorf("properties: can not bind %s: %v", field.key, err)
+		}
+	}
+
+	return props, nil
+}
+
+// boundFields return fields declared with PropTag, nested structs are walked
+func boundFields(typ reflect.Type, index []int) (fields []boundField) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		key := f.Tag.Get(PropTag)
+		if key == "" {
+			if f.Type.Kind() == reflect.Struct && f.PkgPath == "" {
+				fields = append(fields, boundFields(f.Type, fieldIndex)...)
+			}
+			continue
+		}
+
+		fields = append(fields, boundField{
+			index: fieldIndex,
+			key:   strings.ToLower(key),
+			env:   f.Tag.Get(EnvTag),
+			flag:  f.Tag.Get(FlagTag),
+			dflt:  f.Tag.Get(DefaultTag),
+			usage: f.Tag.Get(UsageTag),
+		})
+	}
+	return
+}
+
+// bindFlag bind a flag to a property key which differs from flag name
+func (p *Properties) bindFlag(key, name string) {
+	if p.flagKeys == nil {
+		p.flagKeys = map[string]string{}
+	}
+	p.flagKeys[key] = name
+	p.Viper.BindPFlag(key, p.lookupFlag(name))
+}
+
+// bindEnv bind an environment variable to a property key
+func (p *Properties) bindEnv(key, name string) {
+	if p.envKeys == nil {
+		p.envKeys = map[string]string{}
+	}
+	p.envKeys[key] = name
+	p.Viper.BindEnv(key, name)
+}
+
+// decode a property value into out with viper decoding rules
+func decode(input interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(decoderConfig(out))
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
+
+// decoderConfig return viper default decoder configuration
+func decoderConfig(out interface{}) *mapstructure.DecoderConfig {
+	return &mapstructure.DecoderConfig{
+		Result:           out,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	}
+}
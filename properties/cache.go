@@ -0,0 +1,128 @@
+package properties
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RemoteStatus tells if a remote layer is live or loaded from the offline cache
+type RemoteStatus struct {
+	// Provider of the remote layer
+	Provider RemoteProvider
+
+	// True if settings were loaded from Config.RemoteCacheDir because provider was unreachable
+	Cached bool
+
+	// When settings were fetched from the provider
+	FetchedAt time.Time
+}
+
+// Age return how old settings are
+func (s RemoteStatus) Age() time.Duration {
+	return time.Since(s.FetchedAt)
+}
+
+// String return a one line status, e.g. "consul 127.0.0.1:8500/config/app: cached, 2h0m0s old"
+func (s RemoteStatus) String() string {
+	state := "live"
+	if s.Cached {
+		state = "cached"
+	}
+	return fmt.Sprintf("%s %s%s: %s, %s old", s.Provider.Name, s.Provider.Url, s.Provider.Path, state, s.Age().Round(time.Second))
+}
+
+// RemoteStatus return the status of each remote layer, in load order
+func (p *Properties) RemoteStatus() []RemoteStatus {
+	p.rlock()
+	defer p.runlock()
+	return append([]RemoteStatus{}, p.remoteStatus...)
+}
+
+// remoteCache is the content of a cache file
+type remoteCache struct {
+	Provider  string          `json:"provider"`
+	Timestamp time.Time       `json:"timestamp"`
+	Checksum  string          `json:"checksum"`
+	Settings  json.RawMessage `json:"settings"`
+}
+
+// cacheFile return the cache file path of provider
+func (c Config) cacheFile(provider RemoteProvider) string {
+	sum := sha256.Sum256([]byte(provider.Name + "|" + provider.Url + "|" + provider.Path))
+	return filepath.Join(c.RemoteCacheDir, "remote-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// writeRemoteCache save provider settings under Config.RemoteCacheDir, if set
+func (c Config) writeRemoteCache(provider RemoteProvider, settings map[string]interface{}, fetchedAt time.Time) error {
+	if c.RemoteCacheDir == "" {
+		return nil
+	}
+	content, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	cache, err := json.MarshalIndent(remoteCache{
+		Provider:  provider.Name + " " + provider.Url + provider.Path,
+		Timestamp: fetchedAt,
+		Checksum:  hex.EncodeToString(sum[:]),
+		Settings:  content,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(c.RemoteCacheDir, 0700); err != nil {
+		return err
+	}
+	// write then rename, a reader never sees a partial file
+	file := c.cacheFile(provider)
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, cache, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// readRemoteCache load provider settings from Config.RemoteCacheDir,
+// checking checksum and Config.RemoteCacheMaxAge
+func (c Config) readRemoteCache(provider RemoteProvider) (map[string]interface{}, time.Time, error) {
+	if c.RemoteCacheDir == "" {
+		return nil, time.Time{}, errors.New("no remote cache dir")
+	}
+	content, err := ioutil.ReadFile(c.cacheFile(provider))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var cache remoteCache
+	if err = json.Unmarshal(content, &cache); err != nil {
+		return nil, time.Time{}, fmt.Errorf("corrupted cache: %v", err)
+	}
+	// settings are indented in the file, checksum is on compact settings
+	var compact bytes.Buffer
+	if err = json.Compact(&compact, cache.Settings); err != nil {
+		return nil, time.Time{}, fmt.Errorf("corrupted cache: %v", err)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	if hex.EncodeToString(sum[:]) != cache.Checksum {
+		return nil, time.Time{}, errors.New("corrupted cache: checksum mismatch")
+	}
+	if age := time.Since(cache.Timestamp); c.RemoteCacheMaxAge > 0 && age > c.RemoteCacheMaxAge {
+		return nil, time.Time{}, fmt.Errorf("cache is too old: %s", age.Round(time.Second))
+	}
+
+	var settings map[string]interface{}
+	if err = json.Unmarshal(cache.Settings, &settings); err != nil {
+		return nil, time.Time{}, fmt.Errorf("corrupted cache: %v", err)
+	}
+	return settings, cache.Timestamp, nil
+}
@@ -0,0 +1,54 @@
+package properties
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteCache(t *testing.T) {
+	dir := t.TempDir()
+
+	mem := NewMemorySource()
+	mem.Set("/config/app", map[string]interface{}{"name": "Cake"})
+	RegisterSource("memory-cache", mem)
+
+	c := Config{
+		ConfigType:     "json",
+		Providers:      []RemoteProvider{{Name: "memory-cache", Path: "/config/app"}},
+		RemoteCacheDir: dir,
+	}
+	props := New(c)
+	assert.Len(t, props.RemoteStatus(), 1)
+	assert.False(t, props.RemoteStatus()[0].Cached)
+
+	// provider unreachable, cache is used
+	mem.Delete("/config/app")
+	props = New(c)
+	assert.Equal(t, "Cake", props.GetString("name"))
+	status := props.RemoteStatus()[0]
+	assert.True(t, status.Cached)
+	assert.Contains(t, status.String(), "cached")
+
+	// too old
+	c.RemoteCacheMaxAge = time.Nanosecond
+	_, err := NewE(c)
+	assert.True(t, errors.Is(err, ErrRemoteUnavailable))
+	assert.Contains(t, err.Error(), "too old")
+
+	// corrupted
+	c.RemoteCacheMaxAge = 0
+	file := c.cacheFile(c.Providers[0])
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(file, []byte(string(content[:len(content)-10])+`"Pie"}}}`), 0600))
+	_, err = NewE(c)
+	assert.True(t, errors.Is(err, ErrRemoteUnavailable))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	assert.Empty(t, files)
+}
@@ -1,5 +1,14 @@
 package properties
 
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
 const (
 	// Default config type
 	DefaultConfigType = "json"
@@ -26,6 +35,10 @@ const (
 	ConfigTypeTag = "config-type"
 )
 
+// ModeSeparator separates stacked modes,
+// e.g. myexec --mode "prod,eu-west,canary"
+const ModeSeparator = ","
+
 // Config is a struct that allows to initialize the Properties type with
 // user defined values
 type Config struct {
@@ -38,6 +51,16 @@ type Config struct {
 	// Define to Environement variables to look up
 	EnvVars []string
 
+	// If set, any key of config files can be overridden by an environment variable
+	// with this prefix, e.g. "MYAPP_APP_PLATEFORM_BASEURL" for "app.plateform.baseurl"
+	EnvPrefix string
+
+	// Maps a key to its environment variable name, by default DefaultEnvKeyReplacer
+	EnvKeyReplacer *strings.Replacer
+
+	// Splits environment values of list properties, by default DefaultEnvListSeparator
+	EnvListSeparator string
+
 	// Define the name of config files (without extension) to look up for.
 	// Default: "config"
 	ConfigName string
@@ -45,17 +68,72 @@ type Config struct {
 	// Define the pathes where to lookup for config files
 	ConfigPathes []string
 
+	// If true, base and mode config files of every ConfigPathes entry are merged,
+	// later pathes over earlier ones, e.g. {"/etc/app", "$XDG_CONFIG_HOME/app", "."}.
+	// Otherwise only the first file found is read, like viper does
+	MergeAllPaths bool
+
+	// If set, base and mode config files are also read at the root of this file system,
+	// e.g. an embed.FS or fstest.MapFS, under files found in ConfigPathes.
+	// Use fs.Sub to read a sub directory.
+	FS fs.FS
+
 	// Define the remote provides names:
 	Providers []RemoteProvider
 
+	// If set, each successful remote read is saved in this directory,
+	// and loaded back when providers are unreachable, see Properties.RemoteStatus
+	RemoteCacheDir string
+
+	// Maximum age of the remote cache to be loaded, no limit if 0
+	RemoteCacheMaxAge time.Duration
+
+	// Deadline to read remote providers at startup, retries included, no deadline if 0.
+	// The offline cache is still tried when the deadline is exceeded
+	RemoteTimeout time.Duration
+
 	// Define the flags to lookup for
 	Flags []Flag
 
+	// Flag set where Flags are defined and parsed, by default the global pflag.CommandLine.
+	// Use a dedicated set, e.g. pflag.NewFlagSet("app", pflag.ContinueOnError),
+	// to create several Properties with the same Flags.
+	FlagSet *pflag.FlagSet
+
+	// Arguments parsed for Flags, without program name. By default os.Args[1:]
+	Args []string
+
 	// Overridable Mode Tag to use for test session by default set to DefaultTestModeTag
+	// Can be a ModeSeparator separated list of modes
 	TestModeTag string
 
 	// Overridable default mode
+	// Can be a ModeSeparator separated list of modes, e.g. "prod,eu-west"
 	DefaultConfigMode string
+
+	// Strategy to merge lists of mode files over lists of lower files:
+	// ArrayReplace (default), ArrayAppend, ArrayPrepend or ArrayMergeByKey.
+	// A mode file can choose its own with MergeDirective
+	ArrayMerge string
+
+	// Strategy by key, overriding ArrayMerge, e.g. {"amiauth.batter": "merge-by-key:type"}
+	ArrayMergeKeys map[string]string
+
+	// If true, a "${key}" or "${env:NAME}" reference which can not be resolved is an error,
+	// otherwise it is kept as is
+	StrictInterpolation bool
+
+	// If set, merged settings are checked at the end of LoadModeProperties,
+	// see StructSchema, JSONSchema and Properties.Validate
+	Schema Schema
+
+	// If true, RequiredKeys and Schema are also checked at the end of New, for applications which load no mode
+	CheckOnNew bool
+
+	// Keys which must be set once modes are loaded, with an optional expected type after a colon,
+	// e.g. "app.plateform.baseurl", "rethinkdb.driver-port:int". Types are
+	// string, int, float, bool, duration, list and map. See Properties.CheckRequired
+	RequiredKeys []string
 }
 
 func NewConfig() Config {
@@ -101,10 +179,19 @@ func (c *Config) InitConfig() {
 	return
 }
 
+// args return Args, or the command line arguments if not set
+func (c Config) args() []string {
+	if c.Args == nil {
+		return os.Args[1:]
+	}
+	return c.Args
+}
+
 // Privider is a struct that hold remote providers data
 type RemoteProvider struct {
 
-	// Set the provider's name | must be "ectd" or "consul"
+	// Set the provider's name, a Source registered with RegisterSource:
+	// "etcd", "etcd3", "consul", "http", "https" or your own
 	Name string
 
 	// Set the provider's url : "http://ip:port" for "etcd", "ip:port" for "consul"
@@ -115,6 +202,22 @@ type RemoteProvider struct {
 
 	// If set check the remote provider with encryption using the defined keyFile
 	KeyFile string
+
+	// Format of the remote value, by default Config.ConfigType
+	ConfigType string
+
+	// Number of reads tried before moving to the next provider, 1 if 0
+	Attempts int
+
+	// Wait before the second attempt, doubled after each failed attempt, with random jitter.
+	// Retry immediately if 0
+	Backoff time.Duration
+
+	// Maximum wait between two attempts, no limit if 0
+	MaxBackoff time.Duration
+
+	// Timeout of each attempt, no timeout if 0
+	Timeout time.Duration
 }
 
 // Flag is a struct that stores flags configuration
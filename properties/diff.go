@@ -0,0 +1,118 @@
+package properties
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// Kinds of KeyDiff
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// KeyDiff is a key added, removed or changed from one mode to another
+type KeyDiff struct {
+	Key  string      `json:"key"`
+	Kind string      `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Diff lists keys which differ between two modes, sorted by key
+type Diff []KeyDiff
+
+// DiffModes load config with mode a then with mode b, like LoadModeProperties does,
+// and return keys added, removed and changed by b compared to a.
+// Modes can be stacked, e.g. "prod,eu". Secrets and keys matching DefaultRedactPatterns are redacted.
+func DiffModes(config Config, a, b string) (Diff, error) {
+	from, err := loadMode(config, a)
+	if err != nil {
+		return nil, err
+	}
+	to, err := loadMode(config, b)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for _, key := range append(from.AllKeys(), to.AllKeys()...) {
+		keys[key] = true
+	}
+	delete(keys, ModeTag)
+	var sorted []string
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	diff := Diff{}
+	for _, key := range sorted {
+		before, after := from.Get(key), to.Get(key)
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		d := KeyDiff{Key: key, Kind: DiffChanged, Old: before, New: after}
+		if before == nil {
+			d.Kind = DiffAdded
+		} else if after == nil {
+			d.Kind = DiffRemoved
+		}
+
+		for _, p := range []*Properties{from, to} {
+			if redact, err := p.redacted(key, DefaultRedactPatterns); err == nil && redact {
+				if d.Old != nil {
+					d.Old = Redacted
+				}
+				if d.New != nil {
+					d.New = Redacted
+				}
+			}
+		}
+		diff = append(diff, d)
+	}
+	return diff, nil
+}
+
+// loadMode load config then mode
+func loadMode(config Config, mode string) (*Properties, error) {
+	props, err := NewE(config)
+	if err != nil {
+		return nil, err
+	}
+	props.Set(ModeTag, mode)
+	if err = props.LoadModeE(); err != nil {
+		return nil, fmt.Errorf("mode %s: %w", mode, err)
+	}
+	return props, nil
+}
+
+// WriteText write one line per key: "+ key=new", "- key=old" or "~ key: old -> new"
+func (d Diff) WriteText(w io.Writer) error {
+	for _, kd := range d {
+		var err error
+		switch kd.Kind {
+		case DiffAdded:
+			_, err = fmt.Fprintf(w, "+ %s=%s\n", kd.Key, flatValue(kd.New))
+		case DiffRemoved:
+			_, err = fmt.Fprintf(w, "- %s=%s\n", kd.Key, flatValue(kd.Old))
+		default:
+			_, err = fmt.Fprintf(w, "~ %s: %s -> %s\n", kd.Key, flatValue(kd.Old), flatValue(kd.New))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON write the diff as an indented json array
+func (d Diff) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
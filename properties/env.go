@@ -0,0 +1,79 @@
+package properties
+
+import (
+	"os"
+	"strings"
+)
+
+// Defaults for automatic environment mapping, see Config.EnvPrefix
+var (
+	// DefaultEnvKeyReplacer maps "app.plateform.base-url" to "APP_PLATEFORM_BASE_URL"
+	DefaultEnvKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+	// DefaultEnvListSeparator splits env values of list properties
+	DefaultEnvListSeparator = ","
+)
+
+// EnvName return the environment variable overriding key with Config.EnvPrefix,
+// e.g. "MYAPP_APP_PLATEFORM_BASEURL" for "app.plateform.baseurl".
+// It returns "" without Config.EnvPrefix.
+func (p Properties) EnvName(key string) string {
+	if p.Config.EnvPrefix == "" {
+		return ""
+	}
+	var replacer = p.Config.EnvKeyReplacer
+	if replacer == nil {
+		replacer = DefaultEnvKeyReplacer
+	}
+	return strings.ToUpper(p.Config.EnvPrefix + "_" + replacer.Replace(key))
+}
+
+// applyEnv bind every key from config files and remote to its prefixed env var.
+// It must be called each time a layer is loaded, so keys only defined in a mode file are bound too.
+// Env values of list properties are split with Config.EnvListSeparator and merged over files,
+// scalar values are resolved by viper on read.
+func (p *Properties) applyEnv() {
+	if p.Config.EnvPrefix == "" {
+		return
+	}
+	if p.prefixEnv == nil {
+		p.prefixEnv = map[string]string{}
+	}
+	var separator = p.Config.EnvListSeparator
+	if separator == "" {
+		separator = DefaultEnvListSeparator
+	}
+
+	p.envLists = nil
+	lists := map[string]bool{}
+	for _, layers := range [][]layer{p.remote, p.layers} {
+		for _, l := range layers {
+			for key, value := range l.values {
+				name := p.EnvName(key)
+				if _, isList := value.([]interface{}); isList {
+					if env, ok := os.LookupEnv(name); ok && env != "" && !lists[key] {
+						lists[key] = true
+						list := strings.Split(env, separator)
+						p.envLists = append(p.envLists, layer{source: SourceEnv, path: name, values: map[string]interface{}{key: list}})
+						p.Viper.MergeConfigMap(nest(key, list))
+					}
+					continue
+				}
+				if _, bound := p.prefixEnv[key]; !bound {
+					p.prefixEnv[key] = name
+					p.Viper.BindEnv(key, name)
+				}
+			}
+		}
+	}
+}
+
+// nest return a nested settings map for a dotted key
+func nest(key string, value interface{}) map[string]interface{} {
+	path := strings.Split(key, ".")
+	settings := map[string]interface{}{path[len(path)-1]: value}
+	for i := len(path) - 2; i >= 0; i-- {
+		settings = map[string]interface{}{path[i]: settings}
+	}
+	return settings
+}
@@ -0,0 +1,123 @@
+package properties
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Sentinel errors returned by the error-returning API (NewE, LoadModeE, ...).
+// Use errors.Is to test for them, the returned errors wrap the original cause.
+var (
+	// ErrModeNotSet is returned when no mode is given by flag, env or Config
+	ErrModeNotSet = errors.New("properties: mode is not set")
+
+	// ErrModeUnknown is returned when a mode is not declared in the modes graph, see ModesKey
+	ErrModeUnknown = errors.New("properties: mode is not declared")
+
+	// ErrConfigNotFound is returned when a base or mode config file can not be found
+	ErrConfigNotFound = errors.New("properties: config file not found")
+
+	// ErrConfigInvalid is returned when a config file exists but can not be parsed
+	ErrConfigInvalid = errors.New("properties: config file is invalid")
+
+	// ErrRemoteUnavailable is returned when remote providers can not be read
+	ErrRemoteUnavailable = errors.New("properties: remote provider unavailable")
+
+	// ErrInterpolation is returned on a reference cycle, or an unresolved reference in strict mode
+	ErrInterpolation = errors.New("properties: interpolation failed")
+
+	// ErrSecretUnresolved is returned when a "secret://" reference can not be resolved
+	ErrSecretUnresolved = errors.New("properties: secret unresolved")
+
+	// ErrSchemaViolation is wrapped by *SchemaError when settings do not match Config.Schema
+	ErrSchemaViolation = errors.New("properties: schema violation")
+
+	// ErrRequiredKeys is wrapped by *RequiredError when Config.RequiredKeys are missing or mistyped
+	ErrRequiredKeys = errors.New("properties: required keys missing or mistyped")
+)
+
+// wrapConfigError classify a viper config read error with the matching sentinel error
+func wrapConfigError(name string, err error) error {
+	var notFound viper.ConfigFileNotFoundError
+	if errors.As(err, &notFound) || os.IsNotExist(err) {
+		return &ConfigError{Kind: ErrConfigNotFound, Name: name, Err: err}
+	}
+	return &ConfigError{Kind: ErrConfigInvalid, Name: name, Err: err}
+}
+
+// ConfigError is the failure to find or read a config file.
+// errors.Is matches its Kind, ErrConfigNotFound or ErrConfigInvalid, and errors.As
+// reaches the original cause, e.g. viper.ConfigFileNotFoundError or *fs.PathError.
+type ConfigError struct {
+	// ErrConfigNotFound or ErrConfigInvalid
+	Kind error
+
+	// Config file path or name searched
+	Name string
+
+	// Original error
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%v: %s: %v", e.Kind, e.Name, e.Err)
+}
+
+func (e *ConfigError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderError is the failure to read a remote provider, or its offline cache
+type ProviderError struct {
+	Provider RemoteProvider
+
+	// Number of reads tried, 0 for the offline cache
+	Attempts int
+
+	// True if the offline cache of the provider could not be read
+	Cache bool
+
+	// Error of the last attempt
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	endpoint := e.Provider.Name + " " + e.Provider.Url + e.Provider.Path
+	if e.Cache {
+		return fmt.Sprintf("%s cache: %v", endpoint, e.Err)
+	}
+	if e.Attempts > 1 {
+		return fmt.Sprintf("%s: %d attempts: %v", endpoint, e.Attempts, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", endpoint, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// RemoteError combines the errors of every remote provider read,
+// use errors.As to know which endpoint failed and why. It wraps ErrRemoteUnavailable.
+type RemoteError struct {
+	Errors []*ProviderError
+}
+
+func (e *RemoteError) Error() string {
+	errs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err.Error()
+	}
+	return fmt.Sprintf("%v: %s", ErrRemoteUnavailable, strings.Join(errs, "; "))
+}
+
+func (e *RemoteError) Unwrap() error {
+	return ErrRemoteUnavailable
+}
@@ -0,0 +1,121 @@
+package properties
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Export formats
+const (
+	ExportJSON = "json"
+	ExportYAML = "yaml"
+	ExportTOML = "toml"
+	// ExportFlat writes one sorted key=value line per property, e.g. "db.port=28015"
+	ExportFlat = "flat"
+)
+
+// DefaultRedactPatterns are the key patterns masked by Export when ExportOptions.RedactPatterns is nil
+var DefaultRedactPatterns = []string{"*password*", "*secret*", "*token*"}
+
+// ExportOptions tunes Export
+type ExportOptions struct {
+	// Patterns of keys whose values are replaced by Redacted, matched with path.Match
+	// against lower case keys, e.g. "db.password" matches "*password*".
+	// DefaultRedactPatterns if nil, set an empty slice to only redact secrets.
+	RedactPatterns []string
+}
+
+// Export write the effective configuration, base and mode files, env, flags, remote and overrides merged,
+// to w as json, yaml, toml or flat key=value. Keys are sorted.
+// Values of keys matching redact patterns, and values resolved from secrets, are replaced by Redacted.
+func (p *Properties) Export(w io.Writer, format string, opts ...ExportOptions) error {
+	var opt ExportOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	patterns := opt.RedactPatterns
+	if patterns == nil {
+		patterns = DefaultRedactPatterns
+	}
+
+	keys, values, err := p.exportValues(patterns)
+	if err != nil {
+		return err
+	}
+
+	switch format := strings.ToLower(format); format {
+	case ExportFlat:
+		for _, key := range keys {
+			if _, err := fmt.Fprintf(w, "%s=%s\n", key, flatValue(values[key])); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ExportJSON, ExportYAML, ExportTOML:
+		// viper encoders sort map keys
+		v := viper.New()
+		v.SetConfigType(format)
+		for _, key := range keys {
+			v.Set(key, values[key])
+		}
+		return v.WriteConfigTo(w)
+	default:
+		return fmt.Errorf("properties: unsupported export format %q", format)
+	}
+}
+
+// exportValues return sorted keys and their values, redacted if they match patterns
+func (p Properties) exportValues(patterns []string) ([]string, map[string]interface{}, error) {
+	p.rlock()
+	defer p.runlock()
+
+	keys := p.Viper.AllKeys()
+	sort.Strings(keys)
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		value := p.Viper.Get(key)
+		redact, err := p.redacted(key, patterns)
+		if err != nil {
+			return nil, nil, err
+		}
+		if redact {
+			value = Redacted
+		}
+		values[key] = value
+	}
+	return keys, values, nil
+}
+
+// redacted return true if key value must be masked by Export
+func (p Properties) redacted(key string, patterns []string) (bool, error) {
+	if p.secrets[key] {
+		return true, nil
+	}
+	for _, pattern := range patterns {
+		match, err := path.Match(strings.ToLower(pattern), key)
+		if err != nil {
+			return false, fmt.Errorf("properties: redact pattern %q: %v", pattern, err)
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// flatValue format a value on one line, lists and maps as json
+func flatValue(value interface{}) string {
+	switch value.(type) {
+	case []interface{}, []string, map[string]interface{}:
+		if content, err := json.Marshal(value); err == nil {
+			return string(content)
+		}
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,60 @@
+package properties
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	os.Setenv("PROPERTIES_TEST_EXPORT_KEY", "k3y")
+	defer os.Unsetenv("PROPERTIES_TEST_EXPORT_KEY")
+	dir := writeConfigDir(t, map[string]string{"app.json": `{
+  "name": "Cake",
+  "tags": ["a", "b"],
+  "db": { "port": 28015, "password": "p4ss" },
+  "api": { "key": "secret://env/PROPERTIES_TEST_EXPORT_KEY", "refresh_token": "t0k3n" }
+}`})
+
+	props := New(Config{ConfigPathes: []string{dir}})
+	props.Set("zone", "eu")
+
+	var flat bytes.Buffer
+	assert.NoError(t, props.Export(&flat, ExportFlat))
+	assert.Equal(t, `api.key=******
+api.refresh_token=******
+db.password=******
+db.port=28015
+name=Cake
+tags=["a","b"]
+zone=eu
+`, flat.String())
+
+	for _, format := range []string{ExportJSON, ExportYAML, ExportTOML} {
+		var out bytes.Buffer
+		assert.NoError(t, props.Export(&out, format), format)
+		for _, secret := range []string{"k3y", "t0k3n", "p4ss"} {
+			assert.NotContains(t, out.String(), secret, format)
+		}
+		assert.Contains(t, out.String(), "Cake", format)
+		// stable order
+		var again bytes.Buffer
+		props.Export(&again, format)
+		assert.Equal(t, out.String(), again.String(), format)
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, props.Export(&out, ExportJSON, ExportOptions{RedactPatterns: []string{"name"}}))
+	var settings map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &settings))
+	assert.Equal(t, Redacted, settings["name"])
+	assert.Equal(t, "p4ss", settings["db"].(map[string]interface{})["password"])
+	// secrets are always redacted
+	assert.Equal(t, Redacted, settings["api"].(map[string]interface{})["key"])
+
+	assert.Error(t, props.Export(&out, "xml"))
+	assert.Error(t, props.Export(&out, ExportFlat, ExportOptions{RedactPatterns: []string{"[a"}}))
+}
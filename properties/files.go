@@ -0,0 +1,134 @@
+package properties
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigFile is a base or mode config file found by FindConfigFiles
+type ConfigFile struct {
+	// File path
+	Path string
+
+	// Mode of the file, e.g. "prod" for "prod.app.json", empty for the base file
+	Mode string
+}
+
+// FindConfigFiles return the base file name.configType and mode files mode.name.configType
+// found in dirs, base files first then modes sorted by name.
+func FindConfigFiles(dirs []string, name, configType string) ([]ConfigFile, error) {
+	var bases, modes []ConfigFile
+	suffix := "." + name + "." + configType
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			file := info.Name()
+			switch {
+			case info.IsDir():
+			case file == name+"."+configType:
+				bases = append(bases, ConfigFile{Path: filepath.Join(dir, file)})
+			case strings.HasSuffix(file, suffix) && len(file) > len(suffix):
+				modes = append(modes, ConfigFile{Path: filepath.Join(dir, file), Mode: strings.TrimSuffix(file, suffix)})
+			}
+		}
+	}
+	sort.SliceStable(modes, func(i, j int) bool { return modes[i].Mode < modes[j].Mode })
+	return append(bases, modes...), nil
+}
+
+// SyntaxError is a config file parse error, with its position when the parser gives it.
+// It matches ErrConfigInvalid with errors.Is.
+type SyntaxError struct {
+	Path string
+
+	// Position of the error, 0 if unknown
+	Line, Column int
+
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %v", e.Path, e.Line, e.Column, e.Err)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrConfigInvalid
+}
+
+// lineRegexp finds the line of yaml, toml or properties parser errors
+var lineRegexp = regexp.MustCompile(`line (\d+)`)
+
+// CheckConfigFile parse a config file of configType, json by default.
+// It returns a *SyntaxError if the file can not be parsed.
+func CheckConfigFile(path, configType string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if configType == "" {
+		configType = DefaultConfigType
+	}
+
+	if configType == "json" {
+		// encoding/json gives the offset of the error, viper does not
+		var settings map[string]interface{}
+		if err = json.Unmarshal(content, &settings); err != nil {
+			var offset int64
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) {
+				offset = syntaxErr.Offset
+			} else if errors.As(err, &typeErr) {
+				offset = typeErr.Offset
+			}
+			line, column := position(content, offset)
+			return &SyntaxError{Path: path, Line: line, Column: column, Err: err}
+		}
+		return nil
+	}
+
+	v := viper.New()
+	v.SetConfigType(configType)
+	if err = v.ReadConfig(bytes.NewReader(content)); err != nil {
+		syntaxErr := &SyntaxError{Path: path, Err: err}
+		if match := lineRegexp.FindStringSubmatch(err.Error()); match != nil {
+			syntaxErr.Line, _ = strconv.Atoi(match[1])
+		}
+		return syntaxErr
+	}
+	return nil
+}
+
+// position return line and column, from 1, of offset in content, 0, 0 if offset is unknown
+func position(content []byte, offset int64) (line, column int) {
+	if offset <= 0 || offset > int64(len(content)) {
+		return 0, 0
+	}
+	before := content[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n') - 1
+	return line, column
+}
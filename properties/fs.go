@@ -0,0 +1,104 @@
+package properties
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// fsPrefix marks paths of files read from Config.FS, e.g. "fs:prod.app.json"
+const fsPrefix = "fs:"
+
+// configFile is a base or mode config file loaded, read from fsys if set
+type configFile struct {
+	source string
+	path   string
+	fsys   fs.FS
+}
+
+// findFiles return the config files named name: the one at the root of Config.FS,
+// then the first one found in Config.ConfigPathes, or all of them with Config.MergeAllPaths,
+// merged in this order.
+// It returns ErrConfigNotFound if there is none.
+func (p *Properties) findFiles(source, name, configType string) ([]configFile, error) {
+	var files []configFile
+	if p.Config.FS != nil {
+		file := name + "." + configType
+		_, err := fs.Stat(p.Config.FS, file)
+		if err == nil {
+			files = append(files, configFile{source: source, path: file, fsys: p.Config.FS})
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, wrapConfigError(fsPrefix+file, err)
+		}
+	}
+
+	if len(p.Config.ConfigPathes) > 0 && p.Config.MergeAllPaths {
+		for _, dir := range p.Config.ConfigPathes {
+			file := filepath.Join(os.ExpandEnv(dir), name+"."+configType)
+			info, err := os.Stat(file)
+			switch {
+			case err == nil && !info.IsDir():
+				files = append(files, configFile{source: source, path: file})
+			case err != nil && !os.IsNotExist(err):
+				return nil, wrapConfigError(file, err)
+			}
+		}
+	} else if len(p.Config.ConfigPathes) > 0 {
+		p.Viper.SetConfigName(name)
+		err := p.Viper.MergeInConfig() // Find and read the config file
+		var notFound viper.ConfigFileNotFoundError
+		switch {
+		case err == nil:
+			files = append(files, configFile{source: source, path: p.Viper.ConfigFileUsed()})
+		case !errors.As(err, &notFound) || len(files) == 0:
+			return nil, wrapConfigError(name, err)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, wrapConfigError(name, fs.ErrNotExist)
+	}
+	return files, nil
+}
+
+// searchPaths return the paths findFiles looks for a config file named name
+func (p *Properties) searchPaths(name, configType string) []string {
+	var paths []string
+	file := name + "." + configType
+	if p.Config.FS != nil {
+		paths = append(paths, fsPrefix+file)
+	}
+	for _, dir := range p.Config.ConfigPathes {
+		paths = append(paths, filepath.Join(os.ExpandEnv(dir), file))
+	}
+	return paths
+}
+
+// readFiles read layers of files, with the files they include
+func (p *Properties) readFiles(files []configFile, configType string) ([]layer, error) {
+	var layers []layer
+	for _, file := range files {
+		l, err := p.readFileLayers(file.fsys, file.source, file.path, configType)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l...)
+	}
+	return layers, nil
+}
+
+// ConfigFilesUsed return the config files loaded, base files then mode files,
+// with the files they include, in merge order. Files of Config.FS start with "fs:".
+func (p *Properties) ConfigFilesUsed() []string {
+	p.rlock()
+	defer p.runlock()
+
+	var files []string
+	for _, l := range p.layers {
+		files = append(files, l.path)
+	}
+	return files
+}
@@ -0,0 +1,101 @@
+package properties
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFS(t *testing.T) {
+	embedded := fstest.MapFS{
+		"app.json":         {Data: []byte(`{"name": "Cake", "db": {"host": "embedded", "port": 1}}`)},
+		"prod.app.json":    {Data: []byte(`{"url": "http://embedded.prod.me"}`)},
+		"staging.app.json": {Data: []byte(`{"level": "info"}`)},
+		"dev.app.json":     {Data: []byte(`{"$include": "shared/dev.json"}`)},
+		"shared/dev.json":  {Data: []byte(`{"level": "debug"}`)},
+		"broken.app.json":  {Data: []byte(`{"level": `)},
+	}
+
+	dir := writeConfigDir(t, map[string]string{
+		"app.json":      `{"db": {"port": 2}}`,
+		"prod.app.json": `{"$extends": "staging", "extra": true}`,
+	})
+
+	// on-disk files over embedded ones
+	props := New(Config{FS: embedded, ConfigPathes: []string{dir}, DefaultConfigMode: "prod"})
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, "embedded", props.GetString("db.host"))
+	assert.Equal(t, 2, props.GetInt("db.port"))
+	assert.Equal(t, "http://embedded.prod.me", props.GetString("url"))
+	assert.Equal(t, "info", props.GetString("level"))
+	assert.True(t, props.GetBool("extra"))
+	pv, _ := props.Explain("db.port")
+	assert.Equal(t, filepath.Join(dir, "app.json"), pv.Path)
+	assert.Equal(t, "fs:app.json", pv.Shadowed[0].Path)
+
+	// embedded only
+	props = New(Config{FS: embedded, DefaultConfigMode: "dev"})
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, "Cake", props.GetString("name"))
+	pv, _ = props.Explain("level")
+	assert.Equal(t, SourceMode, pv.Source)
+	assert.Equal(t, "fs:shared/dev.json", pv.Path)
+	assert.True(t, errors.Is(props.Watch(context.Background()), ErrConfigNotFound))
+
+	props.Set(ModeTag, "unknown")
+	assert.True(t, errors.Is(props.LoadModeE(), ErrConfigNotFound))
+	props.Set(ModeTag, "broken")
+	assert.True(t, errors.Is(props.LoadModeE(), ErrConfigInvalid))
+
+	_, err := NewE(Config{FS: fstest.MapFS{}})
+	assert.True(t, errors.Is(err, ErrConfigNotFound))
+}
+
+func TestMergeAllPaths(t *testing.T) {
+	system := writeConfigDir(t, map[string]string{
+		"app.json":      `{"name": "Cake", "db": {"host": "db.system", "port": 1}}`,
+		"prod.app.json": `{"url": "http://system.me"}`,
+	})
+	user := writeConfigDir(t, map[string]string{
+		"prod.app.json": `{"db": {"host": "db.user"}, "level": "info"}`,
+	})
+	local := writeConfigDir(t, map[string]string{
+		"app.json":      `{"db": {"port": 3}}`,
+		"prod.app.json": `{"level": "debug"}`,
+	})
+	os.Setenv("PROPERTIES_TEST_XDG", user)
+	defer os.Unsetenv("PROPERTIES_TEST_XDG")
+
+	c := Config{ConfigPathes: []string{system, "$PROPERTIES_TEST_XDG", local}, MergeAllPaths: true, DefaultConfigMode: "prod"}
+	props := New(c)
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, "Cake", props.GetString("name"))
+	assert.Equal(t, 3, props.GetInt("db.port"))
+	assert.Equal(t, "db.user", props.GetString("db.host"))
+	assert.Equal(t, "debug", props.GetString("level"))
+	assert.Equal(t, "http://system.me", props.GetString("url"))
+	assert.Equal(t, []string{
+		filepath.Join(system, "app.json"),
+		filepath.Join(local, "app.json"),
+		filepath.Join(system, "prod.app.json"),
+		filepath.Join(user, "prod.app.json"),
+		filepath.Join(local, "prod.app.json"),
+	}, props.ConfigFilesUsed())
+
+	// first found only, like viper
+	c.MergeAllPaths = false
+	props = New(c)
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, 1, props.GetInt("db.port"))
+	assert.Equal(t, []string{filepath.Join(system, "app.json"), filepath.Join(system, "prod.app.json")}, props.ConfigFilesUsed())
+
+	c.MergeAllPaths = true
+	c.ConfigName = "missing"
+	_, err := NewE(c)
+	assert.True(t, errors.Is(err, ErrConfigNotFound))
+}
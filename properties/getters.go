@@ -0,0 +1,174 @@
+package properties
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Getters and setters of the embedded viper instance, guarded against concurrent rebuilds
+// of the merged view by Watch and WatchRemote. Internal code reads p.Viper directly.
+
+func (p *Properties) Get(key string) interface{} {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.Get(key)
+}
+
+func (p *Properties) GetString(key string) string {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetString(key)
+}
+
+func (p *Properties) GetBool(key string) bool {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetBool(key)
+}
+
+func (p *Properties) GetInt(key string) int {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetInt(key)
+}
+
+func (p *Properties) GetInt32(key string) int32 {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetInt32(key)
+}
+
+func (p *Properties) GetInt64(key string) int64 {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetInt64(key)
+}
+
+func (p *Properties) GetUint(key string) uint {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetUint(key)
+}
+
+func (p *Properties) GetUint32(key string) uint32 {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetUint32(key)
+}
+
+func (p *Properties) GetUint64(key string) uint64 {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetUint64(key)
+}
+
+func (p *Properties) GetFloat64(key string) float64 {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetFloat64(key)
+}
+
+func (p *Properties) GetTime(key string) time.Time {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetTime(key)
+}
+
+func (p *Properties) GetDuration(key string) time.Duration {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetDuration(key)
+}
+
+func (p *Properties) GetIntSlice(key string) []int {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetIntSlice(key)
+}
+
+func (p *Properties) GetStringSlice(key string) []string {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetStringSlice(key)
+}
+
+func (p *Properties) GetStringMap(key string) map[string]interface{} {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetStringMap(key)
+}
+
+func (p *Properties) GetStringMapString(key string) map[string]string {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetStringMapString(key)
+}
+
+func (p *Properties) GetStringMapStringSlice(key string) map[string][]string {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetStringMapStringSlice(key)
+}
+
+func (p *Properties) GetSizeInBytes(key string) uint {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.GetSizeInBytes(key)
+}
+
+func (p *Properties) IsSet(key string) bool {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.IsSet(key)
+}
+
+func (p *Properties) InConfig(key string) bool {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.InConfig(key)
+}
+
+func (p *Properties) AllKeys() []string {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.AllKeys()
+}
+
+func (p *Properties) AllSettings() map[string]interface{} {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.AllSettings()
+}
+
+// Sub return a copy of the key sub tree, it does not follow later reloads
+func (p *Properties) Sub(key string) *viper.Viper {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.Sub(key)
+}
+
+func (p *Properties) Unmarshal(rawVal interface{}, opts ...viper.DecoderConfigOption) error {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.Unmarshal(rawVal, opts...)
+}
+
+func (p *Properties) UnmarshalKey(key string, rawVal interface{}, opts ...viper.DecoderConfigOption) error {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.UnmarshalKey(key, rawVal, opts...)
+}
+
+func (p *Properties) UnmarshalExact(rawVal interface{}, opts ...viper.DecoderConfigOption) error {
+	p.rlock()
+	defer p.runlock()
+	return p.Viper.UnmarshalExact(rawVal, opts...)
+}
+
+// Set override the value of key like viper does
+func (p *Properties) Set(key string, value interface{}) {
+	p.lock()
+	defer p.unlock()
+	p.Viper.Set(key, value)
+}
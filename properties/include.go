@@ -0,0 +1,106 @@
+package properties
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeDirective is the key of a config file listing files merged under it,
+// e.g. {"$include": ["db.json", "features.json"]}. Paths are relative to the including file,
+// then searched through Config.ConfigPathes. Included files can include others.
+const IncludeDirective = "$include"
+
+// ExtendsDirective is the key of a mode file listing modes loaded before it,
+// e.g. {"$extends": "staging"} in prod.app.json loads staging.app.json then prod.app.json.
+const ExtendsDirective = "$extends"
+
+// readList remove key from settings and return its value as a list of strings
+func readList(settings map[string]interface{}, key string) []string {
+	value, ok := settings[key]
+	delete(settings, key)
+	if !ok {
+		return nil
+	}
+	switch value := value.(type) {
+	case []interface{}:
+		var list []string
+		for _, item := range value {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list
+	case nil:
+		return nil
+	default:
+		return splitModes(fmt.Sprint(value))
+	}
+}
+
+// readFileLayers read a config file, from fsys if set, and the files it includes, included files first.
+// Included layers have the source of the including file and their own path.
+func (p Properties) readFileLayers(fsys fs.FS, source, file, configType string, stack ...string) ([]layer, error) {
+	id := fsPrefix + file
+	if fsys == nil {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		id = abs
+	}
+	for i, included := range stack {
+		if included == id {
+			cycle := append(append([]string{}, stack[i:]...), id)
+			return nil, fmt.Errorf("%w: include cycle: %s", ErrConfigInvalid, strings.Join(cycle, " -> "))
+		}
+	}
+
+	l, err := readFileLayer(fsys, source, file, configType)
+	if err != nil {
+		return nil, err
+	}
+
+	stack = append(append([]string{}, stack...), id)
+	var layers []layer
+	for _, include := range l.include {
+		included, err := p.findInclude(fsys, file, include)
+		if err != nil {
+			return nil, err
+		}
+		includedLayers, err := p.readFileLayers(fsys, source, included, configType, stack...)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, includedLayers...)
+	}
+	return append(layers, l), nil
+}
+
+// findInclude return the path of an included file, relative to the including file,
+// then to one of Config.ConfigPathes, or to the root of fsys
+func (p Properties) findInclude(fsys fs.FS, from, include string) (string, error) {
+	if fsys != nil {
+		for _, candidate := range []string{path.Join(path.Dir(from), include), path.Clean(include)} {
+			if _, err := fs.Stat(fsys, candidate); err == nil {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s included by %s%s", ErrConfigNotFound, include, fsPrefix, from)
+	}
+
+	if filepath.IsAbs(include) {
+		return include, nil
+	}
+	candidates := []string{filepath.Join(filepath.Dir(from), include)}
+	for _, dir := range p.Config.ConfigPathes {
+		candidates = append(candidates, filepath.Join(dir, include))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s included by %s", ErrConfigNotFound, include, from)
+}
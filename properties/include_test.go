@@ -0,0 +1,62 @@
+package properties
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludeAndExtends(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json":             `{"$include": ["db.json", "shared/features.json"], "name": "Cake", "db": {"port": 1}}`,
+		"db.json":              `{"db": {"host": "localhost", "port": 28015}}`,
+		"shared/features.json": `{"$include": "flags.json", "features": {"search": true}}`,
+		"flags.json":           `{"features": {"beta": false}}`,
+		"staging.app.json":     `{"url": "http://staging.me", "level": "debug"}`,
+		"prod.app.json":        `{"$extends": "staging", "url": "http://prod.me"}`,
+		"cycle.json":           `{"$include": ["cycle2.json"]}`,
+		"cycle2.json":          `{"$include": ["cycle.json"]}`,
+		"loop.app.json":        `{"$extends": ["loop2"]}`,
+		"loop2.app.json":       `{"$extends": ["loop"]}`,
+		"missing.app.json":     `{"$include": ["missing.json"]}`,
+	})
+
+	c := Config{ConfigPathes: []string{dir}, DefaultConfigMode: "prod"}
+	props := New(c)
+	assert.NoError(t, props.LoadModeE())
+
+	assert.Equal(t, "localhost", props.GetString("db.host"))
+	assert.Equal(t, 1, props.GetInt("db.port"))
+	assert.True(t, props.GetBool("features.search"))
+	assert.True(t, props.IsSet("features.beta"))
+	assert.Nil(t, props.Get(IncludeDirective))
+	assert.Equal(t, "http://prod.me", props.GetString("url"))
+	assert.Equal(t, "debug", props.GetString("level"))
+
+	pv, _ := props.Explain("db.host")
+	assert.Equal(t, SourceFile, pv.Source)
+	assert.Equal(t, filepath.Join(dir, "db.json"), pv.Path)
+	pv, _ = props.Explain("db.port")
+	assert.Equal(t, filepath.Join(dir, "app.json"), pv.Path)
+	assert.Equal(t, filepath.Join(dir, "db.json"), pv.Shadowed[0].Path)
+	pv, _ = props.Explain("features.beta")
+	assert.Equal(t, filepath.Join(dir, "flags.json"), pv.Path)
+	pv, _ = props.Explain("level")
+	assert.Equal(t, SourceMode, pv.Source)
+	assert.Equal(t, filepath.Join(dir, "staging.app.json"), pv.Path)
+
+	_, err := NewE(Config{ConfigPathes: []string{dir}, ConfigName: "cycle"})
+	assert.True(t, errors.Is(err, ErrConfigInvalid))
+	assert.Contains(t, err.Error(), "include cycle")
+
+	props.Set(ModeTag, "loop")
+	err = props.LoadModeE()
+	assert.True(t, errors.Is(err, ErrConfigInvalid))
+	assert.Contains(t, err.Error(), "extends cycle: loop -> loop2 -> loop")
+
+	props.Set(ModeTag, "missing")
+	err = props.LoadModeE()
+	assert.True(t, errors.Is(err, ErrConfigNotFound))
+}
@@ -0,0 +1,186 @@
+package properties
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Interpolation syntax in string values of config files:
+//
+//	"successurl": "${amiauth.baseurl}/private"  reference to another property
+//	"data": "${env:HOME}/data"                  reference to an environment variable
+//	"literal": "$${not.a.reference}"            escaped, gives "${not.a.reference}"
+const (
+	interpolationStart  = "${"
+	interpolationEscape = "$${"
+	interpolationEnd    = "}"
+	interpolationEnv    = "env:"
+)
+
+// errInterpolationCycle is wrapped by a reference cycle error, which is never kept as is
+var errInterpolationCycle = errors.New("cycle")
+
+// interpolator resolves references of one interpolation pass
+type interpolator struct {
+	p        *Properties
+	raw      map[string]interface{}
+	strict   bool
+	resolved map[string]interface{}
+	stack    []string
+}
+
+// interpolate resolve references in string values of config files and remote,
+// referenced values are read with env and flags overrides.
+// Resolved values are merged over files, so they must be resolved again each time a layer is loaded.
+// It returns ErrInterpolation on cycles, or on unresolved references with Config.StrictInterpolation.
+func (p *Properties) interpolate() error {
+	raw := map[string]interface{}{}
+	for _, layers := range [][]layer{p.remote, p.layers} {
+		for _, l := range layers {
+			for key, value := range l.values {
+				raw[key] = value
+			}
+		}
+	}
+
+	in := interpolator{p: p, raw: raw, strict: p.Config.StrictInterpolation, resolved: map[string]interface{}{}}
+
+	var keys []string
+	for key, value := range raw {
+		if s, ok := value.(string); ok && strings.Contains(s, interpolationStart) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var errs []string
+	for _, key := range keys {
+		if _, err := in.resolve(key); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s", ErrInterpolation, strings.Join(errs, "; "))
+	}
+
+	p.interpolated = map[string]interface{}{}
+	for _, key := range keys {
+		// env and flags still win over resolved file values
+		if _, overridden := p.envOrFlagValue(key); !overridden {
+			p.interpolated[key] = in.resolved[key]
+			p.Viper.MergeConfigMap(nest(key, in.resolved[key]))
+		}
+	}
+	return nil
+}
+
+// resolve return the value of key with its references resolved
+func (in *interpolator) resolve(key string) (interface{}, error) {
+	if v, ok := in.resolved[key]; ok {
+		return v, nil
+	}
+	for i, k := range in.stack {
+		if k == key {
+			return nil, fmt.Errorf("%w %s", errInterpolationCycle, strings.Join(append(in.stack[i:], key), " -> "))
+		}
+	}
+
+	value, exists := in.p.envOrFlagValue(key)
+	if !exists {
+		value, exists = in.raw[key]
+	}
+	if !exists {
+		value = in.p.Viper.Get(key)
+		if value == nil {
+			return nil, fmt.Errorf("unresolved reference %s", key)
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		in.stack = append(in.stack, key)
+		v, err := in.expand(s)
+		in.stack = in.stack[:len(in.stack)-1]
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+
+	in.resolved[key] = value
+	return value, nil
+}
+
+// expand resolve every reference of s. A value made of a single reference keeps the referenced type.
+func (in *interpolator) expand(s string) (interface{}, error) {
+	if strings.HasPrefix(s, interpolationStart) && strings.Index(s, interpolationEnd) == len(s)-1 {
+		return in.reference(s[len(interpolationStart) : len(s)-1])
+	}
+
+	var b strings.Builder
+	for len(s) > 0 {
+		if strings.HasPrefix(s, interpolationEscape) {
+			b.WriteString(interpolationStart)
+			s = s[len(interpolationEscape):]
+			continue
+		}
+		if !strings.HasPrefix(s, interpolationStart) {
+			b.WriteByte(s[0])
+			s = s[1:]
+			continue
+		}
+
+		end := strings.Index(s, interpolationEnd)
+		if end < 0 {
+			b.WriteString(s)
+			break
+		}
+		v, err := in.reference(s[len(interpolationStart):end])
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(fmt.Sprint(v))
+		s = s[end+len(interpolationEnd):]
+	}
+	return b.String(), nil
+}
+
+// reference return a referenced property or env value,
+// an unresolved reference is kept as is unless interpolation is strict.
+func (in *interpolator) reference(ref string) (interface{}, error) {
+	var v interface{}
+	var err error
+	if strings.HasPrefix(ref, interpolationEnv) {
+		name := strings.TrimPrefix(ref, interpolationEnv)
+		if env, ok := os.LookupEnv(name); ok {
+			v = env
+		} else {
+			err = fmt.Errorf("unresolved reference %s%s%s", interpolationStart, ref, interpolationEnd)
+		}
+	} else {
+		v, err = in.resolve(strings.ToLower(strings.TrimSpace(ref)))
+	}
+
+	if err != nil {
+		if in.strict || errors.Is(err, errInterpolationCycle) {
+			return nil, err
+		}
+		return interpolationStart + ref + interpolationEnd, nil
+	}
+	return v, nil
+}
+
+// envOrFlagValue return key value if set by an env var or a flag
+func (p Properties) envOrFlagValue(key string) (interface{}, bool) {
+	for _, l := range p.dynamicLayers() {
+		if l.source != SourceEnv && l.source != SourceFlag {
+			continue
+		}
+		if v, ok := l.values[key]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
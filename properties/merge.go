@@ -0,0 +1,244 @@
+package properties
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Array merge strategies, see Config.ArrayMerge
+const (
+	// ArrayReplace replaces lower lists by mode lists, like viper does
+	ArrayReplace = "replace"
+	// ArrayAppend appends mode items after lower items
+	ArrayAppend = "append"
+	// ArrayPrepend puts mode items before lower items
+	ArrayPrepend = "prepend"
+	// ArrayMergeByKey merges items sharing the same "id", or the field given after a colon,
+	// e.g. "merge-by-key:type". Other mode items are appended.
+	ArrayMergeByKey = "merge-by-key"
+)
+
+// DefaultArrayMergeField is the item field used by ArrayMergeByKey when none is given
+const DefaultArrayMergeField = "id"
+
+// MergeDirective is the key of a mode file choosing array merge strategies of this file by key,
+// e.g. {"$merge": {"amiauth.batter": "merge-by-key:type", "*": "append"}}, "*" for every list.
+// It overrides Config.ArrayMerge and Config.ArrayMergeKeys, and is not part of settings.
+const MergeDirective = "$merge"
+
+// DeleteDirective is a tombstone value: a mode file key set to "$delete", or to null,
+// removes this key, or subtree, defined by lower files. Get, IsSet and Unmarshal act
+// as if it was never defined in config files.
+const DeleteDirective = "$delete"
+
+// readDirectives remove directives from settings read from a config file and return the merge ones
+func readDirectives(settings map[string]interface{}) map[string]string {
+	directive, ok := settings[MergeDirective]
+	delete(settings, MergeDirective)
+	if !ok {
+		return nil
+	}
+	merge := map[string]string{}
+	if sub, ok := directive.(map[string]interface{}); ok {
+		for key, strategy := range flatten("", sub) {
+			merge[strings.ToLower(key)] = fmt.Sprint(strategy)
+		}
+	}
+	return merge
+}
+
+// arrayStrategy return the merge strategy of list key for layer l,
+// from the layer directive, then Config.ArrayMergeKeys, then Config.ArrayMerge
+func (p Properties) arrayStrategy(l layer, key string) string {
+	if strategy, ok := l.merge[key]; ok {
+		return strategy
+	}
+	if strategy, ok := l.merge["*"]; ok {
+		return strategy
+	}
+	for k, strategy := range p.Config.ArrayMergeKeys {
+		if strings.ToLower(k) == key {
+			return strategy
+		}
+	}
+	if p.Config.ArrayMerge != "" {
+		return p.Config.ArrayMerge
+	}
+	return ArrayReplace
+}
+
+// mergeFiles rebuild viper config from base and mode file layers, merged left to right
+// with array merge strategies and tombstones, over remote layers. Merged lists are kept in mode layers for provenance.
+func (p *Properties) mergeFiles(configType string) error {
+	merged := map[string]interface{}{}
+	for i := range p.layers {
+		l := &p.layers[i]
+		var err error
+		merged, err = p.mergeSettings(*l, "", merged, l.settings)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrConfigInvalid, l.path, err)
+		}
+		l.values = flatten("", l.settings)
+		for key, value := range l.values {
+			if _, ok := value.([]interface{}); ok {
+				l.values[key] = lookupKey(merged, key)
+			}
+		}
+	}
+
+	// keys deleted by tombstones are hidden from every layer
+	live := flatten("", merged)
+	for _, l := range p.layers {
+		for key := range l.values {
+			if _, ok := live[key]; !ok {
+				delete(l.values, key)
+			}
+		}
+	}
+
+	// remote layers are under config files, whatever the array merge strategies
+	remote := map[string]interface{}{}
+	replace := layer{merge: map[string]string{"*": ArrayReplace}}
+	for _, l := range append(append([]layer{}, p.remote...), layer{settings: merged}) {
+		var err error
+		if remote, err = p.mergeSettings(replace, "", remote, l.settings); err != nil {
+			return err
+		}
+	}
+	merged = remote
+
+	// reset viper config, then set merged settings
+	p.Viper.SetConfigType(DefaultConfigType)
+	err := p.Viper.ReadConfig(strings.NewReader("{}"))
+	p.Viper.SetConfigType(configType)
+	if err != nil {
+		return err
+	}
+	return p.Viper.MergeConfigMap(merged)
+}
+
+// mergeSettings return a copy of dst with src merged over it
+func (p Properties) mergeSettings(l layer, prefix string, dst, src map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{}, len(dst)+len(src))
+	for key, value := range dst {
+		merged[key] = value
+	}
+	for key, value := range src {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		if value == nil || value == DeleteDirective {
+			delete(merged, key)
+			continue
+		}
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			sub, ok := merged[key].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+			}
+			sub, err := p.mergeSettings(l, path, sub, value)
+			if err != nil {
+				return nil, err
+			}
+			merged[key] = sub
+		case []interface{}:
+			list, ok := merged[key].([]interface{})
+			if !ok {
+				merged[key] = value
+				continue
+			}
+			list, err := p.mergeList(l, path, list, value)
+			if err != nil {
+				return nil, err
+			}
+			merged[key] = list
+		default:
+			merged[key] = value
+		}
+	}
+	return merged, nil
+}
+
+// mergeList merge src list over dst list with the strategy of key
+func (p Properties) mergeList(l layer, key string, dst, src []interface{}) ([]interface{}, error) {
+	strategy := p.arrayStrategy(l, key)
+	field := DefaultArrayMergeField
+	if i := strings.Index(strategy, ":"); i >= 0 {
+		strategy, field = strategy[:i], strategy[i+1:]
+	}
+
+	switch strategy {
+	case ArrayReplace:
+		return src, nil
+	case ArrayAppend:
+		return append(append([]interface{}{}, dst...), src...), nil
+	case ArrayPrepend:
+		return append(append([]interface{}{}, src...), dst...), nil
+	case ArrayMergeByKey:
+		merged := append([]interface{}{}, dst...)
+	items:
+		for _, item := range src {
+			if id, ok := itemField(item, field); ok {
+				for i, lower := range merged {
+					if lowerID, ok := itemField(lower, field); ok && lowerID == id {
+						var err error
+						merged[i], err = p.mergeSettings(l, key, lower.(map[string]interface{}), item.(map[string]interface{}))
+						if err != nil {
+							return nil, err
+						}
+						continue items
+					}
+				}
+			}
+			merged = append(merged, item)
+		}
+		return merged, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown array merge strategy %q", key, strategy)
+	}
+}
+
+// itemField return the field value of a list item as a string, ok is false if item is not a map with field
+func itemField(item interface{}, field string) (string, bool) {
+	settings, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	for key, value := range settings {
+		if strings.EqualFold(key, field) {
+			return fmt.Sprint(value), true
+		}
+	}
+	return "", false
+}
+
+// setKey set the value of a dotted key in nested settings, creating missing maps
+func setKey(settings map[string]interface{}, key string, value interface{}) {
+	path := strings.Split(key, ".")
+	for _, k := range path[:len(path)-1] {
+		sub, ok := settings[k].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			settings[k] = sub
+		}
+		settings = sub
+	}
+	settings[path[len(path)-1]] = value
+}
+
+// lookupKey return the value of a dotted key in nested settings
+func lookupKey(settings map[string]interface{}, key string) interface{} {
+	path := strings.Split(key, ".")
+	for _, k := range path[:len(path)-1] {
+		sub, ok := settings[k].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		settings = sub
+	}
+	return settings[path[len(path)-1]]
+}
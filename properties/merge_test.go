@@ -0,0 +1,117 @@
+package properties
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayMergeStrategies(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json": `{
+  "tags": ["a", "b"],
+  "hosts": ["h1"],
+  "batter": [{ "type": "Regular", "price": 1 }, { "type": "Chocolate", "price": 2 }]
+}`,
+		"prod.app.json": `{
+  "tags": ["c"],
+  "hosts": ["h2"],
+  "batter": [{ "type": "Chocolate", "price": 3 }, { "type": "Devil's Food", "price": 4 }]
+}`,
+		"eu.app.json": `{
+  "$merge": { "*": "prepend", "hosts": "replace" },
+  "tags": ["eu"],
+  "hosts": ["h3"]
+}`,
+	})
+
+	load := func(c Config) (*Properties, error) {
+		c.ConfigPathes = []string{dir}
+		props, err := NewE(c)
+		if err != nil {
+			return nil, err
+		}
+		return props, props.LoadModeE()
+	}
+
+	// viper behavior by default
+	props, err := load(Config{DefaultConfigMode: "prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, props.GetStringSlice("tags"))
+	assert.Len(t, props.Get("batter"), 2)
+
+	props, err = load(Config{
+		DefaultConfigMode: "prod,eu",
+		ArrayMerge:        ArrayAppend,
+		ArrayMergeKeys:    map[string]string{"Batter": "merge-by-key:type"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eu", "a", "b", "c"}, props.GetStringSlice("tags"))
+	assert.Equal(t, []string{"h3"}, props.GetStringSlice("hosts"))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "Regular", "price": float64(1)},
+		map[string]interface{}{"type": "Chocolate", "price": float64(3)},
+		map[string]interface{}{"type": "Devil's Food", "price": float64(4)},
+	}, props.Get("batter"))
+	assert.Nil(t, props.Get(MergeDirective))
+
+	pv, _ := props.Explain("tags")
+	assert.Equal(t, SourceMode, pv.Source)
+	assert.Equal(t, []interface{}{"eu", "a", "b", "c"}, pv.Value)
+
+	_, err = load(Config{DefaultConfigMode: "prod", ArrayMerge: "shuffle"})
+	assert.True(t, errors.Is(err, ErrConfigInvalid))
+}
+
+func TestTombstones(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json": `{
+  "name": "Cake",
+  "rethinkdb": { "driver-port": 12345, "dbname": "primimo" },
+  "amiauth": { "baseurl": "http://myapp.com", "successurl": "http://myapp.com/private" }
+}`,
+		"test.app.json": `{
+  "rethinkdb": null,
+  "amiauth": { "successurl": "$delete" },
+  "cache": { "ttl": null }
+}`,
+		"local.app.json": `{
+  "rethinkdb": { "dbname": "local" }
+}`,
+	})
+
+	c := Config{ConfigPathes: []string{dir}, DefaultConfigMode: "test"}
+	props := New(c)
+	assert.NoError(t, props.LoadModeE())
+
+	assert.False(t, props.IsSet("rethinkdb"))
+	assert.False(t, props.IsSet("rethinkdb.dbname"))
+	assert.Nil(t, props.Get("amiauth.successurl"))
+	assert.Equal(t, "http://myapp.com", props.GetString("amiauth.baseurl"))
+	assert.False(t, props.IsSet("cache.ttl"))
+	assert.NotContains(t, props.AllKeys(), "cache.ttl")
+	_, ok := props.Explain("rethinkdb.dbname")
+	assert.False(t, ok)
+
+	var settings struct {
+		Name      string
+		Rethinkdb map[string]interface{}
+		Amiauth   map[string]string
+	}
+	assert.NoError(t, props.Unmarshal(&settings))
+	assert.Nil(t, settings.Rethinkdb)
+	assert.Equal(t, map[string]string{"baseurl": "http://myapp.com"}, settings.Amiauth)
+
+	// a later mode defines the key again
+	props.Set(ModeTag, "test,local")
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, "local", props.GetString("rethinkdb.dbname"))
+	assert.False(t, props.IsSet("rethinkdb.driver-port"))
+
+	// base is kept for other modes
+	props.Set(ModeTag, "local")
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, 12345, props.GetInt("rethinkdb.driver-port"))
+	assert.Equal(t, "http://myapp.com/private", props.GetString("amiauth.successurl"))
+}
@@ -0,0 +1,123 @@
+package properties
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ModesKey is the key of the base config declaring the modes graph, e.g.
+//
+//	"modes": {"prod": {"extends": ["common-cloud"]}, "canary": {"extends": ["prod"]}, "dev": {"extends": []}}
+//
+// When declared, only the modes of the graph, declared or extended, can be loaded,
+// each one after the modes it extends. Like directives, it is not part of settings.
+// A ModesKey value which is not a map of declarations with an "extends" list only,
+// e.g. a list, is an application setting.
+const ModesKey = "modes"
+
+// readModeGraph remove the modes graph from settings read from a base file and return it,
+// nil if ModesKey is not a modes graph.
+func readModeGraph(settings map[string]interface{}) map[string][]string {
+	modes, ok := settings[ModesKey].(map[string]interface{})
+	if !ok || len(modes) == 0 {
+		return nil
+	}
+	graph := map[string][]string{}
+	for mode, declaration := range modes {
+		fields, ok := declaration.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for key := range fields {
+			if key != "extends" {
+				return nil
+			}
+		}
+		graph[mode] = readList(copySettings(fields), "extends")
+	}
+	delete(settings, ModesKey)
+	return graph
+}
+
+// modeGraph return the modes declared under ModesKey by base files with the modes they extend,
+// nil if not declared. A mode declared by several files takes the last declaration.
+func (props Properties) modeGraph() map[string][]string {
+	var graph map[string][]string
+	for _, l := range props.layers {
+		if l.source != SourceFile || l.modes == nil {
+			continue
+		}
+		if graph == nil {
+			graph = map[string][]string{}
+		}
+		for mode, parents := range l.modes {
+			graph[mode] = parents
+		}
+	}
+	// extended modes are part of the graph, with no parent unless declared
+	for _, parents := range graph {
+		for _, parent := range parents {
+			if _, ok := graph[strings.ToLower(parent)]; !ok {
+				graph[strings.ToLower(parent)] = nil
+			}
+		}
+	}
+	return graph
+}
+
+// checkMode return ErrModeUnknown if graph is declared and mode is not part of it
+func checkMode(mode string, graph map[string][]string) error {
+	if _, declared := graph[strings.ToLower(mode)]; graph == nil || declared {
+		return nil
+	}
+	var names []string
+	for name := range graph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w: %s, declared modes: %s", ErrModeUnknown, mode, strings.Join(names, ", "))
+}
+
+// resolveModes return modes with the modes they extend in graph, in merge order:
+// every mode after the modes it extends, then in the given order.
+// With a graph, a mode which is not declared is an error. A mode extending itself is an error.
+func resolveModes(modes []string, graph map[string][]string) ([]string, error) {
+	var chain []string
+	done := map[string]bool{}
+
+	var visit func(mode string, stack []string) error
+	visit = func(mode string, stack []string) error {
+		for i, m := range stack {
+			if m == mode {
+				cycle := append(append([]string{}, stack[i:]...), mode)
+				return fmt.Errorf("%w: mode cycle: %s", ErrConfigInvalid, strings.Join(cycle, " -> "))
+			}
+		}
+		if done[mode] {
+			return nil
+		}
+
+		if err := checkMode(mode, graph); err != nil {
+			return err
+		}
+		parents := graph[strings.ToLower(mode)]
+
+		stack = append(append([]string{}, stack...), mode)
+		for _, parent := range parents {
+			if err := visit(parent, stack); err != nil {
+				return err
+			}
+		}
+		done[mode] = true
+		chain = append(chain, mode)
+		return nil
+	}
+
+	for _, mode := range modes {
+		if err := visit(mode, nil); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
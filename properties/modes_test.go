@@ -0,0 +1,109 @@
+package properties
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModesGraph(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json": `{"name": "Cake", "url": "http://localhost", "modes": {
+			"prod": {"extends": ["common-cloud"]},
+			"canary": {"extends": "prod"},
+			"eu": {"extends": []}
+		}}`,
+		"common-cloud.app.json": `{"url": "http://cloud.me", "level": "info", "region": "us"}`,
+		"prod.app.json":         `{"url": "http://prod.me"}`,
+		"canary.app.json":       `{"level": "debug"}`,
+		"eu.app.json":           `{"region": "eu"}`,
+		"dev.app.json":          `{"level": "trace"}`,
+	})
+
+	props := New(Config{ConfigPathes: []string{dir}, DefaultConfigMode: "canary,eu"})
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, []string{"common-cloud", "prod", "canary", "eu"}, props.Modes())
+	assert.Equal(t, "http://prod.me", props.GetString("url"))
+	assert.Equal(t, "debug", props.GetString("level"))
+	assert.Equal(t, "eu", props.GetString("region"))
+	assert.Equal(t, "Cake", props.GetString("name"))
+	// the graph is not a setting
+	assert.False(t, props.IsSet(ModesKey))
+	assert.NotContains(t, props.AllSettings(), ModesKey)
+	type appConfig struct {
+		Name, Url, Level, Region string
+	}
+	props.Config.Schema = StructSchema(&appConfig{})
+	assert.NoError(t, props.Validate())
+
+	// a mode requested after its parent is loaded once
+	props = New(Config{ConfigPathes: []string{dir}, DefaultConfigMode: "prod,common-cloud"})
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, []string{"common-cloud", "prod"}, props.Modes())
+	assert.Equal(t, "http://prod.me", props.GetString("url"))
+
+	props = New(Config{ConfigPathes: []string{dir}, DefaultConfigMode: "dev"})
+	err := props.LoadModeE()
+	assert.True(t, errors.Is(err, ErrModeUnknown))
+	assert.Contains(t, err.Error(), "dev, declared modes: canary, common-cloud, eu, prod")
+	assert.Panics(t, func() {
+		New(Config{ConfigPathes: []string{dir}, DefaultConfigMode: "dev"}).LoadModeProperties(false)
+	})
+}
+
+func TestResolveModes(t *testing.T) {
+	chain, err := resolveModes([]string{"test", "eu"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test", "eu"}, chain)
+
+	graph := map[string][]string{
+		"base":   nil,
+		"cloud":  {"base"},
+		"prod":   {"cloud", "base"},
+		"canary": {"prod", "cloud"},
+	}
+	chain, err = resolveModes([]string{"canary"}, graph)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"base", "cloud", "prod", "canary"}, chain)
+
+	_, err = resolveModes([]string{"prod"}, map[string][]string{"prod": {"stage"}})
+	assert.True(t, errors.Is(err, ErrModeUnknown))
+
+	_, err = resolveModes([]string{"a"}, map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}})
+	assert.True(t, errors.Is(err, ErrConfigInvalid))
+	assert.Contains(t, err.Error(), "mode cycle: a -> b -> c -> a")
+}
+
+func TestModesGraphExtendsDirective(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json":            `{"modes": {"prod": {"extends": []}, "staging": {"extends": []}, "canary": {"extends": []}}}`,
+		"staging.app.json":    `{"level": "info"}`,
+		"prod.app.json":       `{"$extends": "staging", "url": "http://prod.me"}`,
+		"canary.app.json":     `{"$extends": "experiment"}`,
+		"experiment.app.json": `{"level": "debug"}`,
+	})
+
+	props := New(Config{ConfigPathes: []string{dir}, DefaultConfigMode: "prod"})
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, []string{"staging", "prod"}, props.Modes())
+	assert.Equal(t, "info", props.GetString("level"))
+
+	props = New(Config{ConfigPathes: []string{dir}, DefaultConfigMode: "canary"})
+	err := props.LoadModeE()
+	assert.True(t, errors.Is(err, ErrModeUnknown))
+	assert.Contains(t, err.Error(), "experiment")
+}
+
+func TestModesSetting(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json":      `{"modes": ["fast", "safe"], "engines": {"modes": {"fast": {"speed": 2}}}}`,
+		"prod.app.json": `{"url": "http://prod.me"}`,
+	})
+
+	// not a modes graph, kept as settings
+	props := New(Config{ConfigPathes: []string{dir}, DefaultConfigMode: "prod"})
+	assert.NoError(t, props.LoadModeE())
+	assert.Equal(t, []string{"fast", "safe"}, props.GetStringSlice(ModesKey))
+	assert.Equal(t, 2, props.GetInt("engines.modes.fast.speed"))
+}
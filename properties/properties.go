@@ -1,7 +1,13 @@
 package properties
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -13,11 +19,62 @@ import (
 type Properties struct {
 	*viper.Viper
 	Config Config
+
+	// base and mode config files loaded, in merge order, used to rebuild the merged view on change
+	files []configFile
+
+	// mode files searched but not found by the last LoadModeProperties
+	notFound []string
+
+	// values read from base and mode files, then from remote providers, by precedence
+	layers []layer
+	remote []layer
+
+	// remote providers read and their status, one per remote layer
+	providers    []RemoteProvider
+	remoteStatus []RemoteStatus
+
+	// property keys bound to a flag or env var with another name, see Bind
+	flagKeys map[string]string
+	envKeys  map[string]string
+
+	// values set by SetDefault, flattened
+	defaults map[string]interface{}
+
+	// property keys bound to an env var with Config.EnvPrefix, and env list values merged over files
+	prefixEnv map[string]string
+	envLists  []layer
+
+	// values of config files with ${...} and secret references resolved
+	interpolated map[string]interface{}
+
+	// keys resolved from a secret reference, redacted in dumps
+	secrets map[string]bool
+
+	// callbacks fired when the merged view is rebuilt
+	onChange []ChangeFunc
+
+	// serializes rebuilds from file and remote watchers, and guards reads against them
+	mu *sync.RWMutex
 }
 
 // Properties constructor
 // Settings default values if need
+// Panic if configuration can not be loaded, see NewE to handle errors.
 func New(config ...Config) *Properties {
+	prop, err := NewE(config...)
+	if err != nil {
+		log.Panic(err)
+	}
+	return prop
+}
+
+// NewE is the error-returning Properties constructor.
+// It returns ErrConfigNotFound, ErrConfigInvalid, ErrRemoteUnavailable, ErrInterpolation
+// or ErrSecretUnresolved wrapping the original cause when configuration can not be loaded.
+// Config.RequiredKeys and Config.Schema are checked by LoadModeProperties on the merged view,
+// or here with Config.CheckOnNew.
+func NewE(config ...Config) (*Properties, error) {
 	var c Config
 
 	if len(config) == 0 {
@@ -27,24 +84,40 @@ func New(config ...Config) *Properties {
 	}
 	c.InitConfig()
 
-	prop := Properties{Config: c, Viper: viper.New()}
-	prop.init()
+	prop := Properties{Config: c, Viper: viper.New(), mu: &sync.RWMutex{}}
+	if err := prop.init(); err != nil {
+		return nil, err
+	}
+	if c.CheckOnNew {
+		if err := prop.CheckRequired(); err != nil {
+			return nil, err
+		}
+		if err := prop.Validate(); err != nil {
+			return nil, err
+		}
+	}
 
-	return &prop
+	return &prop, nil
 }
 
 //initializes the properties instance - make calls to p.Viper library to initilize configuration.
-func (p Properties) init() {
+func (p *Properties) init() error {
 
 	//gives an instance of viper to Properties instance
 
 	//Bind flags
 	if p.Config.Flags != nil && len(p.Config.Flags) > 0 {
+		var flagSet = p.flagSet()
 		for _, flag := range p.Config.Flags {
-			pflag.String(flag.Name, flag.Default, flag.Usage)
-			p.Viper.BindPFlag(flag.Name, pflag.Lookup(flag.Name))
+			// a flag already defined by another instance on the same set is reused
+			if flagSet.Lookup(flag.Name) == nil {
+				flagSet.String(flag.Name, flag.Default, flag.Usage)
+			}
+			p.Viper.BindPFlag(flag.Name, flagSet.Lookup(flag.Name))
+		}
+		if err := flagSet.Parse(p.Config.args()); err != nil {
+			return err
 		}
-		pflag.Parse()
 	}
 
 	//Bind Env vars :
@@ -55,47 +128,159 @@ func (p Properties) init() {
 	}
 
 	//Set config file name
-	var configName = p.GetStringOrDefault(ConfigNameTag, p.Config.ConfigName)
-	var configType = p.GetStringOrDefault(ConfigTypeTag, p.Config.ConfigType)
+	var configName = p.stringOrDefault(ConfigNameTag, p.Config.ConfigName)
+	var configType = p.stringOrDefault(ConfigTypeTag, p.Config.ConfigType)
 
 	p.Viper.SetConfigName(configName)
 	p.Viper.SetConfigType(configType)
 
 	//set the lookup pathes for config files from overloading flags and env
-	var configDir = p.GetStringOrDefault(ConfigDirTag, "")
+	var configDir = p.stringOrDefault(ConfigDirTag, "")
 	if configDir != "" {
 		p.Config.ConfigPathes = []string{configDir}
 	}
 
-	if p.Config.ConfigPathes != nil && len(p.Config.ConfigPathes) > 0 {
+	if len(p.Config.ConfigPathes) > 0 || p.Config.FS != nil {
 		for _, path := range p.Config.ConfigPathes {
 			p.Viper.AddConfigPath(path)
 		}
-		err := p.Viper.ReadInConfig()
+		files, err := p.findFiles(SourceFile, configName, configType)
 		if err != nil {
-			log.Panic(err)
+			return err
+		}
+		p.files = files
+		if p.layers, err = p.readFiles(files, configType); err != nil {
+			return err
 		}
 	}
 
 	//Set remote providers
 	if p.Config.Providers != nil && len(p.Config.Providers) > 0 {
-		for _, provider := range p.Config.Providers {
-			if provider.KeyFile != "" {
-				p.Viper.AddSecureRemoteProvider(provider.Name, provider.Url, provider.Path, provider.KeyFile)
-			} else {
-				p.Viper.AddRemoteProvider(provider.Name, provider.Url, provider.Path)
-			}
+		err := p.readRemote(configType, p.Config.Providers)
+		if err != nil {
+			return err
 		}
-		err := p.Viper.ReadRemoteConfig()
-		if err == nil {
-			log.Panic(err)
+	}
+
+	//Merge config files over remote providers
+	if err := p.mergeFiles(configType); err != nil {
+		return err
+	}
+
+	//Bind prefixed env vars for every loaded key
+	p.applyEnv()
+
+	//Resolve ${...} then secret references
+	if err := p.interpolate(); err != nil {
+		return err
+	}
+	return p.resolveSecrets()
+}
+
+// readRemote read the first reachable provider, like viper does with ReadRemoteConfig.
+// Remote values are kept under config files, env and flags, as viper key/value store layer,
+// they are merged by mergeFiles.
+// Each provider is read from the Source registered for its name, with its retry policy,
+// all within Config.RemoteTimeout. It returns a *RemoteError with the error of each provider.
+func (p *Properties) readRemote(configType string, providers []RemoteProvider) error {
+	ctx := context.Background()
+	if p.Config.RemoteTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Config.RemoteTimeout)
+		defer cancel()
+	}
+
+	remoteErr := &RemoteError{}
+	for _, provider := range providers {
+		if provider.ConfigType == "" {
+			provider.ConfigType = configType
+		}
+		if ctx.Err() != nil {
+			remoteErr.Errors = append(remoteErr.Errors, &ProviderError{Provider: provider, Err: ctx.Err()})
+			continue
+		}
+		settings, attempts, err := fetchRetry(ctx, provider)
+		if err != nil {
+			remoteErr.Errors = append(remoteErr.Errors, &ProviderError{Provider: provider, Attempts: attempts, Err: err})
+			continue
+		}
+		fetchedAt := time.Now()
+		if err = p.Config.writeRemoteCache(provider, settings, fetchedAt); err != nil {
+			log.Printf("Error writing remote config cache: %s \n", err)
+		}
+		p.applyRemote(provider, settings, RemoteStatus{Provider: provider, FetchedAt: fetchedAt})
+		return nil
+	}
+
+	//Fallback on offline cache of first provider cached
+	if p.Config.RemoteCacheDir != "" {
+		for _, provider := range providers {
+			settings, fetchedAt, err := p.Config.readRemoteCache(provider)
+			if err != nil {
+				remoteErr.Errors = append(remoteErr.Errors, &ProviderError{Provider: provider, Cache: true, Err: err})
+				continue
+			}
+			status := RemoteStatus{Provider: provider, Cached: true, FetchedAt: fetchedAt}
+			log.Printf("Remote config unavailable, using %s \n", status)
+			p.applyRemote(provider, normalizeSettings(settings), status)
+			return nil
 		}
 	}
+	return remoteErr
+}
 
+// fetchRemote read provider settings from its Source within provider Timeout, with viper lower case keys
+func fetchRemote(ctx context.Context, provider RemoteProvider) (map[string]interface{}, error) {
+	source, err := lookupSource(provider.Name)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := fetchSource(ctx, source, provider)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeSettings(settings), nil
+}
+
+// normalizeSettings return a copy of settings with viper lower case keys
+func normalizeSettings(settings map[string]interface{}) map[string]interface{} {
+	v := viper.New()
+	v.MergeConfigMap(copySettings(settings))
+	return v.AllSettings()
+}
+
+// applyRemote add remote settings as a layer under config files
+func (p *Properties) applyRemote(provider RemoteProvider, settings map[string]interface{}, status RemoteStatus) {
+	p.providers = append(p.providers, provider)
+	p.remoteStatus = append(p.remoteStatus, status)
+	p.remote = append(p.remote, remoteLayer(provider, settings))
+}
+
+// remoteLayer return the layer of remote provider settings
+func remoteLayer(provider RemoteProvider, settings map[string]interface{}) layer {
+	return layer{source: SourceRemote, path: provider.Url + provider.Path, values: flatten("", settings), settings: settings}
+}
+
+// SetDefault set the default value of key like viper does, it is reported as SourceDefault by Explain
+func (p *Properties) SetDefault(key string, value interface{}) {
+	p.lock()
+	defer p.unlock()
+	if p.defaults == nil {
+		p.defaults = map[string]interface{}{}
+	}
+	key = strings.ToLower(key)
+	if sub, ok := value.(map[string]interface{}); ok && len(sub) > 0 {
+		for k, v := range flatten(key, sub) {
+			p.defaults[strings.ToLower(k)] = v
+		}
+	} else {
+		p.defaults[key] = value
+	}
+	p.Viper.SetDefault(key, value)
 }
 
 // GetOrDie get key, if not found panic
-func (p Properties) GetOrDie(key string) interface{} {
+func (p *Properties) GetOrDie(key string) interface{} {
 	if v := p.Get(key); v == nil {
 		log.Panicf("Required property %s is not found!!", key)
 	} else {
@@ -105,7 +290,7 @@ func (p Properties) GetOrDie(key string) interface{} {
 }
 
 // GetOrDie get key, if not found panic
-func (p Properties) GetSubOrDie(key string) *viper.Viper {
+func (p *Properties) GetSubOrDie(key string) *viper.Viper {
 	if v := p.Sub(key); v == nil {
 		log.Panicf("Required sub properties \"%s\" are not found!!", key)
 	} else {
@@ -115,8 +300,14 @@ func (p Properties) GetSubOrDie(key string) *viper.Viper {
 }
 
 // GetStringOrDefault get string or a default value
-func (p Properties) GetStringOrDefault(key string, dlft string) string {
-	if v := p.GetString(key); v == "" {
+func (p *Properties) GetStringOrDefault(key string, dlft string) string {
+	p.rlock()
+	defer p.runlock()
+	return p.stringOrDefault(key, dlft)
+}
+
+func (p Properties) stringOrDefault(key string, dlft string) string {
+	if v := p.Viper.GetString(key); v == "" {
 		return dlft
 	} else {
 		return v
@@ -124,25 +315,41 @@ func (p Properties) GetStringOrDefault(key string, dlft string) string {
 }
 
 // TryLoadRemoteProperties try load configuration from remote througth Viper
-func (p Properties) TryLoadRemoteProperties() {
+// Panic if remote provider can not be read, see TryLoadRemotePropertiesE to handle errors.
+func (p *Properties) TryLoadRemoteProperties() {
+	if err := p.TryLoadRemotePropertiesE(); err != nil {
+		log.Panic(err)
+	}
+}
+
+// TryLoadRemotePropertiesE try load configuration from remote througth Viper
+// Retry policy is read from "remote.attempts", "remote.backoff" and "remote.timeout" keys.
+// Remote values are resolved like New does, with env, references and secrets.
+// It returns a *RemoteError wrapping ErrRemoteUnavailable if remote provider can not be read,
+// ErrInterpolation or ErrSecretUnresolved if references can not be resolved.
+func (p *Properties) TryLoadRemotePropertiesE() error {
 	var name = p.GetString("remote.name")
 	var url = p.GetString("remote.url")
 	var path = p.GetString("remote.path")
 	var key = p.GetString("remote.key")
+	var attempts = p.GetInt("remote.attempts")
+	var backoff = p.GetDuration("remote.backoff")
+	var timeout = p.GetDuration("remote.timeout")
 	if name != "" && url != "" && path != "" {
-		if key != "" {
-			p.AddSecureRemoteProvider(name, url, path, key)
-		} else {
-			p.AddRemoteProvider(name, url, path)
-		}
-
-		err := p.Viper.ReadRemoteConfig()
+		var configType = p.GetStringOrDefault(ConfigTypeTag, p.Config.ConfigType)
+		p.lock()
+		defer p.unlock()
+		err := p.readRemote(configType, []RemoteProvider{{
+			Name: name, Url: url, Path: path, KeyFile: key,
+			Attempts: attempts, Backoff: backoff, Timeout: timeout,
+		}})
 		if err != nil {
-			// Handle errors reading the config file
-			log.Panicf("Fatal error config Remote provider: %s \n", err)
+			return err
 		}
+		return p.rebuild(configType)
 	}
 
+	return nil
 }
 
 // GetDefaultModeProperties get A Default Property set for classic app based on Flag with
@@ -154,50 +361,209 @@ func (c Config) GetDefaultModeProperties() *Properties {
 }
 
 // CheckRunInTestEnvironment return true if this application is running with 'go test'
+// and go test flags are added to the global pflag set.
 func CheckRunInTestEnvironment() bool {
-	if pflag.Lookup("test.v") == nil {
+	return checkRunInTestEnvironment(pflag.CommandLine)
+}
+
+// RunInTestEnvironment return true if this application is running with 'go test'
+// and go test flags are added to Config.FlagSet, or to the global pflag set.
+func (p Properties) RunInTestEnvironment() bool {
+	return checkRunInTestEnvironment(p.flagSet()) || CheckRunInTestEnvironment()
+}
+
+func checkRunInTestEnvironment(flagSet *pflag.FlagSet) bool {
+	if flagSet.Lookup("test.v") == nil {
 		return false
 	} else {
 		return true
 	}
 }
 
+// flagSet return Config.FlagSet or the global pflag set if not set
+func (p Properties) flagSet() *pflag.FlagSet {
+	if p.Config.FlagSet != nil {
+		return p.Config.FlagSet
+	}
+	return pflag.CommandLine
+}
+
 // Helper to Load Properties and merge it with mode related Properties
 // path will be use by default if user not provide a ConfigDirTag in command line
 // defaultMode will be use by default if user not provide a ModeTag in command line
+// Mode can be a comma separated list, e.g. "prod,eu-west,canary", each mode file
+// is merged left to right on top of the base.
 // props is used as properties base.
-// panicOnModeLoad if true, when loading mode properties failed call "panic" otherwise "warning"
+// panicOnModeLoad if true, when loading a mode properties failed call "panic" otherwise "warning"
+// and continue with next mode.
+// With a modes graph declared in the base config, see ModesKey, the modes each mode extends
+// are loaded before it.
+// A mode which is not set or not declared, a mode cycle, an unresolved reference,
+// a missing required key or a schema violation always panic, see LoadModeE to handle errors.
 func (props *Properties) LoadModeProperties(panicOnModeLoad bool) *Properties {
+	props.lock()
+	err := props.loadModes(func(err error) error {
+		if panicOnModeLoad {
+			return err
+		}
+		log.Printf("Fatal error config mode: %s \n", err)
+		return nil
+	})
+	props.unlock()
+	if err != nil {
+		log.Panicf("Fatal error config mode: %s \n", err)
+	}
 
-	var configName = props.GetStringOrDefault(ConfigNameTag, props.Config.ConfigName)
-	var configType = props.GetStringOrDefault(ConfigTypeTag, props.Config.ConfigType)
+	return props
+}
+
+// LoadModeE load mode related Properties and merge it with current ones.
+// It stops at first mode which can not be merged.
+// It returns ErrModeNotSet if no mode is given, ErrModeUnknown if a mode is not declared
+// in the modes graph, ErrConfigNotFound or ErrConfigInvalid
+// wrapping the cause if a mode config file can not be merged, ErrInterpolation or
+// ErrSecretUnresolved if references can not be resolved, a *RequiredError if Config.RequiredKeys
+// are missing, or a *SchemaError if merged settings do not match Config.Schema.
+func (props *Properties) LoadModeE() error {
+	props.lock()
+	defer props.unlock()
+	return props.loadModes(func(err error) error {
+		return err
+	})
+}
+
+// Modes return the list of modes loaded, in merge order
+func (props *Properties) Modes() []string {
+	props.rlock()
+	defer props.runlock()
+	return props.modes()
+}
+
+func (props Properties) modes() []string {
+	return splitModes(props.Viper.GetString(ModeTag))
+}
 
-	props.SetConfigType(configType)
-	var modeStr = props.GetString(ModeTag)
-	if modeStr == "" {
-		if isInTest := CheckRunInTestEnvironment(); isInTest == true {
+// loadModes merge each mode config file left to right,
+// onModeError decide if a mode config file error stops loading.
+func (props *Properties) loadModes(onModeError func(error) error) error {
+
+	var configName = props.stringOrDefault(ConfigNameTag, props.Config.ConfigName)
+	var configType = props.stringOrDefault(ConfigTypeTag, props.Config.ConfigType)
+
+	props.Viper.SetConfigType(configType)
+	var modeStr = props.Viper.GetString(ModeTag)
+	if len(splitModes(modeStr)) == 0 {
+		if isInTest := props.RunInTestEnvironment(); isInTest == true {
 			modeStr = props.Config.TestModeTag
 		} else {
 			modeStr = props.Config.DefaultConfigMode
 		}
-		if modeStr == "" {
-			log.Panic("Mode is not set !")
+	}
+	modes := splitModes(modeStr)
+	if len(modes) == 0 {
+		return ErrModeNotSet
+	}
+	graph := props.modeGraph()
+	modes, err := resolveModes(modes, graph)
+	if err != nil {
+		return err
+	}
+
+	// keep base files and their includes
+	var files []configFile
+	for _, file := range props.files {
+		if file.source == SourceFile {
+			files = append(files, file)
+		}
+	}
+	props.files = files
+	var base []layer
+	for _, l := range props.layers {
+		if l.source == SourceFile {
+			base = append(base, l)
+		}
+	}
+	props.layers = base
+
+	props.notFound = nil
+	var loaded []string
+	for _, mode := range modes {
+		if err := props.loadMode(mode, configName, configType, graph, &loaded); err != nil {
+			if err = onModeError(err); err != nil {
+				return err
+			}
 		}
 	}
-	props.Set(ModeTag, modeStr)
-	modeConfigName := modeStr + "." + configName
-	props.SetConfigName(modeConfigName)
+	// modes merged, with the modes they extend
+	props.Viper.Set(ModeTag, strings.Join(loaded, ModeSeparator))
+	if err := props.mergeFiles(configType); err != nil {
+		return err
+	}
+	props.applyEnv()
+	if err := props.interpolate(); err != nil {
+		return err
+	}
+	if err := props.resolveSecrets(); err != nil {
+		return err
+	}
+	if err := props.checkRequired(); err != nil {
+		return err
+	}
+
+	return props.validate()
+}
 
-	err := props.MergeInConfig() // Find and read the config file
+// loadMode append the layers of a mode config file, after the modes it extends, and add mode to loaded.
+// A mode already loaded is skipped, a mode extending itself or a mode out of graph is an error.
+func (props *Properties) loadMode(mode, configName, configType string, graph map[string][]string, loaded *[]string, stack ...string) error {
+	for i, m := range stack {
+		if m == mode {
+			cycle := append(append([]string{}, stack[i:]...), mode)
+			return fmt.Errorf("%w: extends cycle: %s", ErrConfigInvalid, strings.Join(cycle, " -> "))
+		}
+	}
+	for _, m := range *loaded {
+		if m == mode {
+			return nil
+		}
+	}
+	if err := checkMode(mode, graph); err != nil {
+		return err
+	}
+
+	files, err := props.findFiles(SourceMode, mode+"."+configName, configType)
+	if errors.Is(err, ErrConfigNotFound) {
+		props.notFound = append(props.notFound, props.searchPaths(mode+"."+configName, configType)...)
+	}
 	if err != nil {
-		if panicOnModeLoad {
-			log.Panicf("Fatal error config mode %s : %s \n", modeConfigName, err)
-		} else {
-			log.Printf("Fatal error config mode %s : %s \n", modeConfigName, err)
-			return props
+		return err
+	}
+	layers, err := props.readFiles(files, configType)
+	if err != nil {
+		return err
+	}
+
+	stack = append(append([]string{}, stack...), mode)
+	for _, l := range layers {
+		for _, parent := range l.extends {
+			if err = props.loadMode(parent, configName, configType, graph, loaded, stack...); err != nil {
+				return err
+			}
 		}
 	}
 
-	return props
+	*loaded = append(*loaded, mode)
+	props.files = append(props.files, files...)
+	props.layers = append(props.layers, layers...)
+	return nil
+}
 
+// splitModes split a ModeSeparator separated list of modes, blank modes are ignored
+func splitModes(modeStr string) (modes []string) {
+	for _, mode := range strings.Split(modeStr, ModeSeparator) {
+		if mode = strings.TrimSpace(mode); mode != "" {
+			modes = append(modes, mode)
+		}
+	}
+	return
 }
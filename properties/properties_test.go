@@ -2,8 +2,13 @@ package properties
 
 import (
 	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
@@ -290,4 +295,51 @@ func TestGetOrDie(t *testing.T) {
 		src.GetOrDie("user.notExist")
 	}, "Calling GetOrDie() should panic")
 
-}
\ No newline at end of file
+}
+func TestIsolatedFlagSet(t *testing.T) {
+	newProps := func(args ...string) *Properties {
+		return New(Config{
+			ConfigType: "json",
+			Flags: []Flag{
+				{"mode", "prod", "Execution mode: 'dev' or 'prod'"},
+			},
+			FlagSet: pflag.NewFlagSet("app", pflag.ContinueOnError),
+			Args:    args,
+		})
+	}
+
+	dev := newProps("--mode", "dev")
+	prod := newProps()
+	assert.Equal(t, "dev", dev.GetString("mode"))
+	assert.Equal(t, "prod", prod.GetString("mode"))
+	assert.False(t, dev.RunInTestEnvironment())
+
+	_, err := NewE(Config{
+		Flags:   []Flag{{"mode", "prod", "Execution mode"}},
+		FlagSet: pflag.NewFlagSet("app", pflag.ContinueOnError),
+		Args:    []string{"--unknown"},
+	})
+	assert.Error(t, err)
+
+	flagSet := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	flagSet.AddGoFlagSet(flag.CommandLine)
+	test := New(Config{ConfigType: "json", FlagSet: flagSet})
+	assert.True(t, test.RunInTestEnvironment())
+}
+
+// writeConfigDir write files, by slash separated path, in a temp dir removed at the end of the test
+// and return the dir
+func writeConfigDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,182 @@
+// Package propertiestest helps testing code built on properties:
+// Properties from an inline map or a fixture directory, per test overrides,
+// and a check of every mode of a fixture directory.
+// Properties are created on their own flag set, the global pflag set is not touched.
+package propertiestest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heirko/go-contrib/properties"
+	"github.com/spf13/pflag"
+)
+
+// Properties wraps properties.Properties with test helpers
+type Properties struct {
+	*properties.Properties
+
+	// how Properties were loaded, and values set since, to load them again when an override is restored
+	dir    string
+	mode   string
+	config properties.Config
+	sets   []*setting
+}
+
+// setting is a value set by Set or Override
+type setting struct {
+	key   string
+	value interface{}
+}
+
+// FromMap return Properties with settings as base config file, e.g.
+//
+//	props := propertiestest.FromMap(t, map[string]interface{}{"db": map[string]interface{}{"port": 28015}})
+//
+// config is used for everything but config files. The test fails if settings can not be loaded.
+func FromMap(t testing.TB, settings map[string]interface{}, config ...properties.Config) *Properties {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "propertiestest")
+	if err != nil {
+		t.Fatalf("propertiestest: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	c := newConfig(config...)
+	c.ConfigName = properties.DefaultConfigName
+	c.ConfigType = properties.DefaultConfigType
+	content, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatalf("propertiestest: %v", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, c.ConfigName+"."+c.ConfigType), content, 0644); err != nil {
+		t.Fatalf("propertiestest: %v", err)
+	}
+	return FromDir(t, dir, "", c)
+}
+
+// FromDir return Properties loaded from base and mode config files of dir, like New then LoadModeProperties.
+// Mode can be a ModeSeparator separated list, no mode is loaded if empty.
+// The test fails if config can not be loaded.
+func FromDir(t testing.TB, dir, mode string, config ...properties.Config) *Properties {
+	t.Helper()
+	props, err := load(dir, mode, config...)
+	if err != nil {
+		t.Fatalf("propertiestest: %v", err)
+	}
+	return props
+}
+
+// load read dir then mode
+func load(dir, mode string, config ...properties.Config) (*Properties, error) {
+	c := newConfig(config...)
+	c.ConfigPathes = []string{dir}
+	props, err := properties.NewE(c)
+	if err != nil {
+		return nil, err
+	}
+	if mode != "" {
+		props.Set(properties.ModeTag, mode)
+		if err = props.LoadModeE(); err != nil {
+			return nil, err
+		}
+	}
+	return &Properties{Properties: props, dir: dir, mode: mode, config: c}, nil
+}
+
+// newConfig return config, or a new one, on its own flag set without command line arguments
+func newConfig(config ...properties.Config) properties.Config {
+	c := properties.NewConfig()
+	if len(config) > 0 {
+		c = config[0]
+	}
+	if c.FlagSet == nil {
+		c.FlagSet = pflag.NewFlagSet("propertiestest", pflag.ContinueOnError)
+	}
+	if c.Args == nil {
+		c.Args = []string{}
+	}
+	return c
+}
+
+// Set set key to value like properties.Properties.Set, the value is kept when an override is restored
+func (p *Properties) Set(key string, value interface{}) {
+	p.sets = append(p.sets, &setting{key: key, value: value})
+	p.Properties.Set(key, value)
+}
+
+// Override set key to value for the test, the previous value is restored by t.Cleanup.
+// Viper can not unset a value, so Properties are loaded again in place, then values of Set
+// and other overrides are set again. Values set on the wrapped Properties directly are lost.
+func (p *Properties) Override(t testing.TB, key string, value interface{}) {
+	t.Helper()
+	s := &setting{key: key, value: value}
+	p.sets = append(p.sets, s)
+	p.Properties.Set(key, value)
+	t.Cleanup(func() {
+		for i := range p.sets {
+			if p.sets[i] == s {
+				p.sets = append(p.sets[:i], p.sets[i+1:]...)
+				break
+			}
+		}
+		if err := p.reload(); err != nil {
+			t.Errorf("propertiestest: restoring %s: %v", key, err)
+		}
+	})
+}
+
+// reload load Properties again in place, pointers to the wrapped Properties stay valid,
+// then set values again in the same order
+func (p *Properties) reload() error {
+	loaded, err := load(p.dir, p.mode, p.config)
+	if err != nil {
+		return err
+	}
+	*p.Properties = *loaded.Properties
+	for _, s := range p.sets {
+		p.Properties.Set(s.key, s.value)
+	}
+	return nil
+}
+
+// AssertModes check that base and every mode file of dir, e.g. "prod.app.json", parse,
+// and that each mode loads over the base with config Schema and RequiredKeys.
+// Every failing mode is reported. It returns true if all modes are valid.
+func AssertModes(t testing.TB, dir string, config ...properties.Config) bool {
+	t.Helper()
+	c := newConfig(config...)
+	c.InitConfig()
+
+	files, err := properties.FindConfigFiles([]string{dir}, c.ConfigName, c.ConfigType)
+	if err != nil {
+		t.Errorf("propertiestest: %v", err)
+		return false
+	}
+	if len(files) == 0 {
+		t.Errorf("propertiestest: no %s.%s config file in %s", c.ConfigName, c.ConfigType, dir)
+		return false
+	}
+
+	ok := true
+	for _, file := range files {
+		if err = properties.CheckConfigFile(file.Path, c.ConfigType); err != nil {
+			t.Errorf("propertiestest: %v", err)
+			ok = false
+			continue
+		}
+		if file.Mode == "" {
+			continue
+		}
+		// a flag set per mode, flags are parsed again
+		c.FlagSet = pflag.NewFlagSet("propertiestest", pflag.ContinueOnError)
+		if _, err = load(dir, file.Mode, c); err != nil {
+			t.Errorf("propertiestest: mode %s: %v", file.Mode, err)
+			ok = false
+		}
+	}
+	return ok
+}
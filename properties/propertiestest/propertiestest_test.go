@@ -0,0 +1,107 @@
+package propertiestest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heirko/go-contrib/properties"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+const resx = "../../properties_test/resx"
+
+func TestFromMapAndOverride(t *testing.T) {
+	props := FromMap(t, map[string]interface{}{
+		"name": "Cake",
+		"db":   map[string]interface{}{"port": 28015},
+	})
+	assert.Equal(t, "Cake", props.GetString("name"))
+	assert.Equal(t, 28015, props.GetInt("db.port"))
+	pv, _ := props.Explain("db.port")
+	assert.Equal(t, properties.SourceFile, pv.Source)
+
+	props.Set("zone", "eu")
+	t.Run("override", func(t *testing.T) {
+		props.Override(t, "db.port", 1234)
+		props.Override(t, "zone", "us")
+		props.Override(t, "new", true)
+		assert.Equal(t, 1234, props.GetInt("db.port"))
+		assert.Equal(t, "us", props.GetString("zone"))
+	})
+	assert.Equal(t, 28015, props.GetInt("db.port"))
+	assert.Equal(t, "eu", props.GetString("zone"))
+	assert.False(t, props.IsSet("new"))
+	pv, _ = props.Explain("db.port")
+	assert.Equal(t, properties.SourceFile, pv.Source)
+	// parent map is read from files again
+	assert.Equal(t, map[string]interface{}{"port": float64(28015)}, props.Get("db"))
+	var db struct{ Port int }
+	assert.NoError(t, props.UnmarshalKey("db", &db))
+	assert.Equal(t, 28015, db.Port)
+
+	wrapped := props.Properties
+	t.Run("override parent", func(t *testing.T) {
+		props.Override(t, "db", map[string]interface{}{"port": 1})
+		assert.Equal(t, 1, props.GetInt("db.port"))
+	})
+	assert.Equal(t, 28015, wrapped.GetInt("db.port"))
+	assert.Equal(t, map[string]interface{}{"port": float64(28015)}, props.Get("db"))
+	pv, _ = props.Explain("db.port")
+	assert.Equal(t, properties.SourceFile, pv.Source)
+	assert.Equal(t, "eu", props.GetString("zone"))
+
+	assert.Nil(t, pflag.CommandLine.Lookup(properties.ModeTag))
+}
+
+func TestFromDir(t *testing.T) {
+	props := FromDir(t, resx, "test,eu")
+	assert.Equal(t, "http://tapp.test.me", props.GetString("app.plateform.baseurl"))
+	assert.Equal(t, "eu", props.GetString("app.plateform.region"))
+
+	c := properties.DefaultConfig()
+	c.DefaultConfigMode = "test"
+	props = FromDir(t, resx, "", c)
+	assert.Equal(t, "http://tapp.me", props.GetString("app.plateform.baseurl"))
+	assert.Nil(t, pflag.CommandLine.Lookup(properties.ConfigDirTag))
+}
+
+// recorder records test errors
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertModes(t *testing.T) {
+	r := &recorder{TB: t}
+	assert.False(t, AssertModes(r, resx))
+	assert.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "testbuggy.app.json:6:")
+
+	dir, err := ioutil.TempDir("", "propertiestest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"app.json":      `{"name": "Cake"}`,
+		"prod.app.json": `{"url": "http://prod.me"}`,
+		"dev.app.json":  `{"url": "${missing}"}`,
+	} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	c := properties.NewConfig()
+	c.RequiredKeys = []string{"url"}
+	assert.True(t, AssertModes(t, dir, c))
+
+	c.StrictInterpolation = true
+	r = &recorder{TB: t}
+	assert.False(t, AssertModes(r, dir, c))
+	assert.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "mode dev")
+}
@@ -0,0 +1,287 @@
+package properties
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Sources a property value can come from, see Provenance
+const (
+	// SourceDefault is a flag default value, or a value set by SetDefault
+	SourceDefault = "default"
+	// SourceRemote is a remote provider, e.g. etcd or consul
+	SourceRemote = "remote"
+	// SourceFile is the base config file, e.g. app.json
+	SourceFile = "file"
+	// SourceMode is a mode config file, e.g. prod.app.json
+	SourceMode = "mode"
+	// SourceEnv is an environment variable from Config.EnvVars
+	SourceEnv = "env"
+	// SourceFlag is a command line flag from Config.Flags
+	SourceFlag = "flag"
+	// SourceOverride is a value set by code with Set
+	SourceOverride = "override"
+)
+
+// Provenance explains where a property value came from
+type Provenance struct {
+	// Property key, lower case as stored by viper
+	Key string
+
+	// Source layer of the value, one of Source* constants
+	Source string
+
+	// Where the value was read: file path, env var name, flag name or provider url
+	Path string
+
+	// Value read from this source
+	Value interface{}
+
+	// True if Value is a resolved secret, redacted by String
+	Secret bool
+
+	// Values of lower precedence sources hidden by this one, highest precedence first
+	Shadowed []Provenance
+}
+
+// String return a one line description, e.g. "app.name=Cake (mode resx/prod.app.json)".
+// Secret values are redacted.
+func (pv Provenance) String() string {
+	var value = pv.Value
+	if pv.Secret {
+		value = Redacted
+	}
+	if pv.Path == "" {
+		return fmt.Sprintf("%s=%v (%s)", pv.Key, value, pv.Source)
+	}
+	return fmt.Sprintf("%s=%v (%s %s)", pv.Key, value, pv.Source, pv.Path)
+}
+
+// layer is a set of flattened values read from one source
+type layer struct {
+	source string
+	path   string
+	values map[string]interface{}
+
+	// nested settings and directives of a config file
+	settings map[string]interface{}
+	merge    map[string]string
+	include  []string
+	extends  []string
+
+	// modes graph declared by a base file, see ModesKey
+	modes map[string][]string
+
+	// file system of the config file, nil for a file on disk
+	fsys fs.FS
+}
+
+// Explain return where key value came from, with the values it shadowed.
+// ok is false if key is not set by any source, or is a parent key, e.g. "app" for "app.name",
+// whose keys can come from different sources.
+func (p *Properties) Explain(key string) (pv Provenance, ok bool) {
+	p.rlock()
+	defer p.runlock()
+	return p.explain(key)
+}
+
+func (p Properties) explain(key string) (pv Provenance, ok bool) {
+	key = strings.ToLower(key)
+
+	// from highest to lowest precedence
+	var found []Provenance
+	for _, l := range p.dynamicLayers() {
+		if v, exists := l.values[key]; exists {
+			found = append(found, Provenance{Key: key, Source: l.source, Path: l.path, Value: v})
+		}
+	}
+
+	current := p.Viper.Get(key)
+	if v, ok := p.interpolated[key]; ok && len(found) > 0 && reflect.DeepEqual(current, v) {
+		// value of the winning file with its references or secret resolved
+		found[0].Value = v
+	}
+	if len(found) == 0 || !reflect.DeepEqual(current, found[0].Value) {
+		if current == nil {
+			return Provenance{Key: key}, false
+		}
+		if _, parent := current.(map[string]interface{}); parent && len(found) == 0 {
+			return Provenance{Key: key}, false
+		}
+		// neither a file, env nor flag, value was set by code
+		found = append([]Provenance{{Key: key, Source: SourceOverride, Value: current}}, found...)
+	}
+
+	pv = found[0]
+	pv.Secret = p.secrets[key]
+	pv.Shadowed = found[1:]
+	return pv, true
+}
+
+// AllProvenance return provenance of every key, sorted by key.
+// Useful for startup logging.
+func (p *Properties) AllProvenance() []Provenance {
+	p.rlock()
+	defer p.runlock()
+
+	keys := map[string]bool{}
+	for _, key := range p.Viper.AllKeys() {
+		keys[key] = true
+	}
+	for _, layers := range [][]layer{p.remote, p.layers} {
+		for _, l := range layers {
+			for key := range l.values {
+				keys[key] = true
+			}
+		}
+	}
+
+	var sorted []string
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var all []Provenance
+	for _, key := range sorted {
+		if pv, ok := p.explain(key); ok {
+			all = append(all, pv)
+		}
+	}
+	return all
+}
+
+// dynamicLayers return every layer from highest to lowest precedence,
+// flags and env are read at call time like viper does.
+func (p Properties) dynamicLayers() []layer {
+	var flags, env, dflt []layer
+	for _, flag := range p.Config.Flags {
+		f := p.lookupFlag(flag.Name)
+		if f == nil {
+			continue
+		}
+		key := strings.ToLower(flag.Name)
+		if f.Changed {
+			flags = append(flags, layer{source: SourceFlag, path: "--" + f.Name, values: map[string]interface{}{key: f.Value.String()}})
+		} else {
+			dflt = append(dflt, layer{source: SourceDefault, path: "--" + f.Name, values: map[string]interface{}{key: f.DefValue}})
+		}
+	}
+	for key, name := range p.flagKeys {
+		if f := p.lookupFlag(name); f != nil && f.Changed {
+			flags = append(flags, layer{source: SourceFlag, path: "--" + f.Name, values: map[string]interface{}{key: f.Value.String()}})
+		} else if f != nil {
+			dflt = append(dflt, layer{source: SourceDefault, path: "--" + f.Name, values: map[string]interface{}{key: f.DefValue}})
+		}
+	}
+	for _, envVar := range p.Config.EnvVars {
+		name := strings.ToUpper(envVar)
+		if v, exists := os.LookupEnv(name); exists {
+			env = append(env, layer{source: SourceEnv, path: name, values: map[string]interface{}{strings.ToLower(envVar): v}})
+		}
+	}
+	for key, name := range p.envKeys {
+		if v, exists := os.LookupEnv(name); exists {
+			env = append(env, layer{source: SourceEnv, path: name, values: map[string]interface{}{key: v}})
+		}
+	}
+	for key, name := range p.prefixEnv {
+		if v, exists := os.LookupEnv(name); exists && v != "" {
+			env = append(env, layer{source: SourceEnv, path: name, values: map[string]interface{}{key: v}})
+		}
+	}
+	env = append(env, p.envLists...)
+
+	all := append(flags, env...)
+	for i := len(p.layers) - 1; i >= 0; i-- {
+		all = append(all, p.layers[i])
+	}
+	for i := len(p.remote) - 1; i >= 0; i-- {
+		all = append(all, p.remote[i])
+	}
+	if len(p.defaults) > 0 {
+		all = append(all, layer{source: SourceDefault, values: p.defaults})
+	}
+	return append(all, dflt...)
+}
+
+// lookupFlag return the flag bound for name
+func (p Properties) lookupFlag(name string) *pflag.Flag {
+	return p.flagSet().Lookup(name)
+}
+
+// readLayer parse content of a config file as a layer
+func readLayer(source, path, configType string, content []byte) (layer, error) {
+	v := viper.New()
+	v.SetConfigType(configType)
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return layer{}, fmt.Errorf("%w: %s: %v", ErrConfigInvalid, path, err)
+	}
+	settings := v.AllSettings()
+	// AllSettings drops null values, keep them as tombstones
+	for _, key := range v.AllKeys() {
+		if v.Get(key) == nil {
+			setKey(settings, key, nil)
+		}
+	}
+	var modes map[string][]string
+	if source == SourceFile {
+		modes = readModeGraph(settings)
+	}
+	return layer{
+		source:   source,
+		path:     path,
+		modes:    modes,
+		merge:    readDirectives(settings),
+		include:  readList(settings, IncludeDirective),
+		extends:  readList(settings, ExtendsDirective),
+		values:   flatten("", settings),
+		settings: settings,
+	}, nil
+}
+
+// readFileLayer read and parse a config file as a layer, from fsys if set
+func readFileLayer(fsys fs.FS, source, file, configType string) (layer, error) {
+	if fsys == nil {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return layer{}, wrapConfigError(file, err)
+		}
+		return readLayer(source, file, configType, content)
+	}
+
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return layer{}, wrapConfigError(fsPrefix+file, err)
+	}
+	l, err := readLayer(source, fsPrefix+file, configType, content)
+	l.fsys = fsys
+	return l, err
+}
+
+// flatten nested settings to dotted keys
+func flatten(prefix string, settings map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+	for key, value := range settings {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if sub, ok := value.(map[string]interface{}); ok && len(sub) > 0 {
+			for subKey, subValue := range flatten(key, sub) {
+				flat[subKey] = subValue
+			}
+			continue
+		}
+		flat[key] = value
+	}
+	return flat
+}
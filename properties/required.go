@@ -0,0 +1,151 @@
+package properties
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// Types a required key can expect, e.g. "db.port:int", see Config.RequiredKeys
+const (
+	TypeString   = "string"
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeDuration = "duration"
+	TypeList     = "list"
+	TypeMap      = "map"
+)
+
+// RequiredError lists every required key missing or mistyped, with the modes and files loaded.
+// It wraps ErrRequiredKeys.
+type RequiredError struct {
+	// Keys not set by any source
+	Missing []string
+
+	// Keys set with a value which does not match the expected type,
+	// e.g. `db.port: want int, got "abc"`
+	Mistyped []string
+
+	// Modes loaded
+	Modes []string
+
+	// Config files and remote providers searched, in merge order
+	Files []string
+
+	// Mode files searched but not found, skipped by LoadModeProperties(false)
+	NotFound []string
+}
+
+func (e *RequiredError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Mistyped) > 0 {
+		parts = append(parts, "mistyped: "+strings.Join(e.Mistyped, ", "))
+	}
+	modes := "none"
+	if len(e.Modes) > 0 {
+		modes = strings.Join(e.Modes, ModeSeparator)
+	}
+	parts = append(parts, "modes: "+modes)
+	files := "none"
+	if len(e.Files) > 0 {
+		files = strings.Join(e.Files, ", ")
+	}
+	parts = append(parts, "searched: "+files)
+	if len(e.NotFound) > 0 {
+		parts = append(parts, "not found: "+strings.Join(e.NotFound, ", "))
+	}
+	return fmt.Sprintf("%v: %s", ErrRequiredKeys, strings.Join(parts, "; "))
+}
+
+func (e *RequiredError) Unwrap() error {
+	return ErrRequiredKeys
+}
+
+// CheckRequired check every key of Config.RequiredKeys is set, with the expected type if any.
+// It is called at the end of LoadModeProperties, and of New with Config.CheckOnNew.
+// It returns a *RequiredError listing every missing or mistyped key.
+func (p *Properties) CheckRequired() error {
+	p.rlock()
+	defer p.runlock()
+	return p.checkRequired()
+}
+
+func (p Properties) checkRequired() error {
+	if len(p.Config.RequiredKeys) == 0 {
+		return nil
+	}
+
+	rerr := &RequiredError{Modes: p.modes()}
+	for _, required := range p.Config.RequiredKeys {
+		key, expected := required, ""
+		if i := strings.LastIndex(required, ":"); i >= 0 {
+			key, expected = required[:i], required[i+1:]
+		}
+		value := p.Viper.Get(key)
+		if value == nil {
+			rerr.Missing = append(rerr.Missing, key)
+			continue
+		}
+		if expected == "" {
+			continue
+		}
+		if ok, err := hasType(value, expected); err != nil {
+			return err
+		} else if !ok {
+			rerr.Mistyped = append(rerr.Mistyped, fmt.Sprintf("%s: want %s, got %#v", key, expected, value))
+		}
+	}
+	if len(rerr.Missing) == 0 && len(rerr.Mistyped) == 0 {
+		return nil
+	}
+
+	for _, layers := range [][]layer{p.remote, p.layers} {
+		for _, l := range layers {
+			rerr.Files = append(rerr.Files, l.path)
+		}
+	}
+	rerr.NotFound = p.notFound
+	return rerr
+}
+
+// hasType return true if value can be read as expected type,
+// string values from env and flags are converted like viper getters do
+func hasType(value interface{}, expected string) (bool, error) {
+	var err error
+	switch expected {
+	case TypeString:
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return false, nil
+		}
+	case TypeInt:
+		// cast truncates decimals, e.g. 28015.5
+		if f, err := cast.ToFloat64E(value); err == nil && f != math.Trunc(f) {
+			return false, nil
+		}
+		_, err = cast.ToIntE(value)
+	case TypeFloat:
+		_, err = cast.ToFloat64E(value)
+	case TypeBool:
+		_, err = cast.ToBoolE(value)
+	case TypeDuration:
+		_, err = cast.ToDurationE(value)
+	case TypeList:
+		switch value.(type) {
+		case []interface{}, []string:
+		default:
+			return false, nil
+		}
+	case TypeMap:
+		_, err = cast.ToStringMapE(value)
+	default:
+		return false, fmt.Errorf("%w: unknown type %q", ErrRequiredKeys, expected)
+	}
+	return err == nil, nil
+}
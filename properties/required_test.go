@@ -0,0 +1,62 @@
+package properties
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasTypeInt(t *testing.T) {
+	for value, expected := range map[interface{}]bool{
+		28015:          true,
+		float64(28015): true,
+		"28015":        true,
+		28015.5:        false,
+		float32(1.5):   false,
+		"28015.5":      false,
+		"abc":          false,
+	} {
+		ok, err := hasType(value, TypeInt)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok, "%#v", value)
+	}
+}
+
+func TestRequiredNotFoundModes(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json":      `{"name": "Cake", "rethinkdb": {"driver-port": 28015.5}}`,
+		"test.app.json": `{"url": "http://test.me"}`,
+	})
+	props := New(Config{
+		ConfigPathes:      []string{dir},
+		DefaultConfigMode: "test,eu",
+		RequiredKeys:      []string{"region", "rethinkdb.driver-port:int"},
+	})
+
+	// missing modes are skipped like LoadModeProperties(false) does
+	err := props.loadModes(func(error) error { return nil })
+	var rerr *RequiredError
+	assert.True(t, errors.As(err, &rerr))
+	assert.Equal(t, []string{"region"}, rerr.Missing)
+	assert.Equal(t, []string{"rethinkdb.driver-port: want int, got 28015.5"}, rerr.Mistyped)
+	assert.Equal(t, []string{filepath.Join(dir, "app.json"), filepath.Join(dir, "test.app.json")}, rerr.Files)
+	assert.Equal(t, []string{filepath.Join(dir, "eu.app.json")}, rerr.NotFound)
+	assert.Contains(t, err.Error(), "not found: "+filepath.Join(dir, "eu.app.json"))
+}
+
+func TestRequiredCheckOnNew(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json": `{"name": "Cake"}`,
+	})
+	c := Config{ConfigPathes: []string{dir}, RequiredKeys: []string{"db.url"}}
+	_, err := NewE(c)
+	assert.NoError(t, err)
+
+	c.CheckOnNew = true
+	_, err = NewE(c)
+	var rerr *RequiredError
+	assert.True(t, errors.As(err, &rerr))
+	assert.Equal(t, []string{"db.url"}, rerr.Missing)
+}
@@ -0,0 +1,65 @@
+package properties
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// fetchRetry read provider settings, retrying as set by provider Attempts, Backoff and MaxBackoff.
+// Each attempt is bounded by provider Timeout. It returns the number of attempts done.
+func fetchRetry(ctx context.Context, provider RemoteProvider) (map[string]interface{}, int, error) {
+	attempts := provider.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var settings map[string]interface{}
+		settings, err = fetchRemote(ctx, provider)
+		if err == nil {
+			return settings, attempt, nil
+		}
+		if attempt == attempts || ctx.Err() != nil {
+			return nil, attempt, err
+		}
+
+		timer := time.NewTimer(backoff(provider, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+// fetchSource read provider settings from source within provider Timeout
+func fetchSource(ctx context.Context, source Source, provider RemoteProvider) (map[string]interface{}, error) {
+	if provider.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, provider.Timeout)
+		defer cancel()
+	}
+	return source.Fetch(ctx, provider)
+}
+
+// backoff return the wait after the failed attempt: Backoff doubled for each previous attempt,
+// capped by MaxBackoff, then randomized between half and full wait so clients do not retry together
+func backoff(provider RemoteProvider, attempt int) time.Duration {
+	wait := provider.Backoff
+	for i := 1; i < attempt && wait > 0; i++ {
+		wait *= 2
+		if provider.MaxBackoff > 0 && wait > provider.MaxBackoff {
+			break
+		}
+	}
+	if provider.MaxBackoff > 0 && wait > provider.MaxBackoff {
+		wait = provider.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
@@ -0,0 +1,85 @@
+package properties
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"name": "Cake"}`)
+	}))
+	defer server.Close()
+
+	props := New(Config{
+		ConfigType: "json",
+		Providers: []RemoteProvider{
+			{Name: "http", Url: server.URL, Path: "/app.json", Attempts: 3, Backoff: time.Millisecond},
+		},
+	})
+	assert.Equal(t, "Cake", props.GetString("name"))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRemoteTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	// per attempt timeout, errors of every provider are combined
+	_, err := NewE(Config{
+		ConfigType: "json",
+		Providers: []RemoteProvider{
+			{Name: "unknown", Url: server.URL, Path: "/app.json"},
+			{Name: "http", Url: server.URL, Path: "/app.json", Attempts: 2, Timeout: 10 * time.Millisecond},
+		},
+	})
+	assert.True(t, errors.Is(err, ErrRemoteUnavailable))
+	var remoteErr *RemoteError
+	assert.True(t, errors.As(err, &remoteErr))
+	assert.Len(t, remoteErr.Errors, 2)
+	assert.Contains(t, remoteErr.Errors[0].Error(), `no source registered for "unknown"`)
+	assert.Equal(t, 2, remoteErr.Errors[1].Attempts)
+	assert.True(t, errors.Is(remoteErr.Errors[1], context.DeadlineExceeded))
+
+	// startup deadline stops retries
+	start := time.Now()
+	_, err = NewE(Config{
+		ConfigType:    "json",
+		RemoteTimeout: 50 * time.Millisecond,
+		Providers: []RemoteProvider{
+			{Name: "http", Url: server.URL, Path: "/app.json", Attempts: 100, Backoff: time.Millisecond},
+			{Name: "http", Url: server.URL, Path: "/other.json"},
+		},
+	})
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.True(t, errors.As(err, &remoteErr))
+	assert.Len(t, remoteErr.Errors, 2)
+	assert.True(t, errors.Is(remoteErr.Errors[1], context.DeadlineExceeded))
+}
+
+func TestBackoff(t *testing.T) {
+	provider := RemoteProvider{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for attempt, max := range map[int]time.Duration{1: 100, 2: 200, 3: 300, 10: 300} {
+		wait := backoff(provider, attempt)
+		assert.True(t, wait >= max*time.Millisecond/2 && wait <= max*time.Millisecond, "attempt %d: %s", attempt, wait)
+	}
+	assert.Equal(t, time.Duration(0), backoff(RemoteProvider{}, 3))
+}
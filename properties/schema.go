@@ -0,0 +1,176 @@
+package properties
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Schema validates merged properties settings, see Config.Schema
+type Schema interface {
+	// Validate return a *SchemaError listing every violation, nil if settings are valid
+	Validate(settings map[string]interface{}) error
+}
+
+// SchemaError reports every schema violation found in one pass
+type SchemaError struct {
+	// Violations found, sorted
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %d violation(s):\n - %s", ErrSchemaViolation, len(e.Violations), strings.Join(e.Violations, "\n - "))
+}
+
+// Unwrap allows errors.Is(err, ErrSchemaViolation)
+func (e *SchemaError) Unwrap() error {
+	return ErrSchemaViolation
+}
+
+// newSchemaError return nil without violations, a sorted *SchemaError otherwise
+func newSchemaError(violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Strings(violations)
+	return &SchemaError{Violations: violations}
+}
+
+// structSchema validates settings against a Go struct
+type structSchema struct {
+	typ reflect.Type
+}
+
+// StructSchema return a Schema checking settings decode into a struct of v type
+// without unknown keys nor type mismatch, then checking its `validate` tags,
+// see github.com/go-playground/validator.
+// Struct fields are matched case insensitively, as viper keys are lower case.
+func StructSchema(v interface{}) Schema {
+	typ := reflect.TypeOf(v)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return structSchema{typ: typ}
+}
+
+func (s structSchema) Validate(settings map[string]interface{}) error {
+	var violations []string
+
+	target := reflect.New(s.typ).Interface()
+	config := decoderConfig(target)
+	config.ErrorUnused = true
+	decoder, err := mapstructure.NewDecoder(config)
+	if err != nil {
+		return err
+	}
+	if err = decoder.Decode(settings); err != nil {
+		if merr, ok := err.(*mapstructure.Error); ok {
+			violations = append(violations, merr.Errors...)
+		} else {
+			violations = append(violations, err.Error())
+		}
+	}
+
+	if err = validator.New().Struct(target); err != nil {
+		if verrs, ok := err.(validator.ValidationErrors); ok {
+			for _, verr := range verrs {
+				violations = append(violations, fmt.Sprintf("'%s' failed on '%s' rule", verr.Namespace(), verr.Tag()))
+			}
+		} else {
+			violations = append(violations, err.Error())
+		}
+	}
+
+	return newSchemaError(violations)
+}
+
+// jsonSchema validates settings against a JSON Schema document
+type jsonSchema struct {
+	loader gojsonschema.JSONLoader
+}
+
+// JSONSchema return a Schema checking settings against a JSON Schema document.
+// Property names in schema must be lower case, as viper keys are.
+func JSONSchema(schema []byte) Schema {
+	return jsonSchema{loader: gojsonschema.NewBytesLoader(schema)}
+}
+
+func (s jsonSchema) Validate(settings map[string]interface{}) error {
+	result, err := gojsonschema.Validate(s.loader, gojsonschema.NewGoLoader(settings))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaViolation, err)
+	}
+
+	var violations []string
+	for _, verr := range result.Errors() {
+		violations = append(violations, verr.String())
+	}
+	return newSchemaError(violations)
+}
+
+// Validate check current settings against Config.Schema, if any.
+// It is called at the end of LoadModeProperties, and of New with Config.CheckOnNew.
+// Keys only set by flags and env, like ModeTag, are not checked.
+// It returns a *SchemaError listing every violation.
+func (p *Properties) Validate() error {
+	p.rlock()
+	defer p.runlock()
+	return p.validate()
+}
+
+func (p Properties) validate() error {
+	if p.Config.Schema == nil {
+		return nil
+	}
+	return p.Config.Schema.Validate(p.schemaSettings())
+}
+
+// schemaSettings return settings without control keys which are not in config files
+func (p Properties) schemaSettings() map[string]interface{} {
+	settings := p.Viper.AllSettings()
+
+	controls := []string{ModeTag}
+	for _, flag := range p.Config.Flags {
+		controls = append(controls, flag.Name)
+	}
+	controls = append(controls, p.Config.EnvVars...)
+
+	for _, key := range controls {
+		if !p.inLayers(strings.ToLower(key)) {
+			deleteKey(settings, strings.ToLower(key))
+		}
+	}
+	return settings
+}
+
+// inLayers return true if key is defined by a file or remote layer
+func (p Properties) inLayers(key string) bool {
+	for _, layers := range [][]layer{p.remote, p.layers} {
+		for _, l := range layers {
+			for k := range l.values {
+				if k == key || strings.HasPrefix(k, key+".") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// deleteKey remove a dotted key from nested settings
+func deleteKey(settings map[string]interface{}, key string) {
+	path := strings.Split(key, ".")
+	for _, k := range path[:len(path)-1] {
+		sub, ok := settings[k].(map[string]interface{})
+		if !ok {
+			return
+		}
+		settings = sub
+	}
+	delete(settings, path[len(path)-1])
+}
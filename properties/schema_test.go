@@ -0,0 +1,108 @@
+package properties
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type schemaPlateform struct {
+	BaseUrl string `validate:"required,url"`
+	Val     struct {
+		T1 int
+	}
+}
+
+type schemaConfig struct {
+	App struct {
+		Plateform schemaPlateform
+	}
+}
+
+func TestStructSchema(t *testing.T) {
+	schema := StructSchema(&schemaConfig{})
+
+	assert.NoError(t, schema.Validate(map[string]interface{}{
+		"app": map[string]interface{}{
+			"plateform": map[string]interface{}{
+				"baseurl": "http://tapp.me",
+				"val":     map[string]interface{}{"t1": 3},
+			},
+		},
+	}))
+
+	err := schema.Validate(map[string]interface{}{
+		"app": map[string]interface{}{
+			"plateform": map[string]interface{}{
+				"base_url": "http://tapp.me",
+				"val":      map[string]interface{}{"t1": "three"},
+			},
+		},
+	})
+	assert.True(t, errors.Is(err, ErrSchemaViolation))
+	var serr *SchemaError
+	assert.True(t, errors.As(err, &serr))
+	// unknown key, type mismatch and missing required value are reported together
+	assert.Len(t, serr.Violations, 3)
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema([]byte(`{
+  "type": "object",
+  "properties": {
+    "name": { "type": "string" },
+    "port": { "type": "integer" }
+  },
+  "required": ["name", "port"],
+  "additionalProperties": false
+}`))
+
+	assert.NoError(t, schema.Validate(map[string]interface{}{"name": "Cake", "port": 12345}))
+
+	err := schema.Validate(map[string]interface{}{"port": "12345", "nmae": "Cake"})
+	var serr *SchemaError
+	assert.True(t, errors.As(err, &serr))
+	assert.Len(t, serr.Violations, 3)
+}
+
+func TestSchemaOnMergedModes(t *testing.T) {
+	type dbConfig struct {
+		Db struct {
+			Host     string `validate:"required"`
+			Password string `validate:"required"`
+		}
+	}
+	dir := writeConfigDir(t, map[string]string{
+		"app.json":      `{"db": {"host": "localhost"}}`,
+		"prod.app.json": `{"db": {"password": "p4ss"}}`,
+	})
+
+	c := Config{ConfigPathes: []string{dir}, DefaultConfigMode: "prod", Schema: StructSchema(&dbConfig{})}
+	props, err := NewE(c)
+	assert.NoError(t, err)
+	// base only view misses the mode key
+	assert.True(t, errors.Is(props.Validate(), ErrSchemaViolation))
+	assert.NoError(t, props.LoadModeE())
+
+	// without modes, checked by New
+	c.CheckOnNew = true
+	_, err = NewE(c)
+	assert.True(t, errors.Is(err, ErrSchemaViolation))
+}
+
+func TestValidateSkipsControlKeys(t *testing.T) {
+	props := New(Config{
+		ConfigType: "json",
+		EnvVars:    []string{"HOME"},
+		Schema: JSONSchema([]byte(`{
+  "type": "object",
+  "additionalProperties": false
+}`)),
+	})
+	props.Set(ModeTag, "test")
+	assert.NoError(t, props.Validate())
+
+	props.Set("name", "Cake")
+	assert.Error(t, props.Validate())
+}
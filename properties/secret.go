@@ -0,0 +1,145 @@
+package properties
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SecretPrefix starts a secret reference value, e.g. "secret://file/run/secrets/db_pass"
+// or "secret://env/DB_PASS". It is followed by the resolver name and the secret path.
+const SecretPrefix = "secret://"
+
+// Redacted replaces secret values in dumps and logs
+const Redacted = "******"
+
+// SecretResolver resolves secret references of one backend
+type SecretResolver interface {
+	// ResolveSecret return the secret at path, e.g. "/run/secrets/db_pass" for
+	// "secret://file/run/secrets/db_pass"
+	ResolveSecret(path string) (string, error)
+}
+
+// SecretResolverFunc is a function usable as SecretResolver
+type SecretResolverFunc func(path string) (string, error)
+
+// ResolveSecret call f(path)
+func (f SecretResolverFunc) ResolveSecret(path string) (string, error) {
+	return f(path)
+}
+
+var (
+	secretResolversMu sync.RWMutex
+	secretResolvers   = map[string]SecretResolver{
+		"file": SecretResolverFunc(fileSecret),
+		"env":  SecretResolverFunc(envSecret),
+	}
+)
+
+// RegisterSecretResolver makes a secret backend available by name,
+// e.g. "vault" resolves "secret://vault/db/password".
+// "file" and "env" are registered by default and can be replaced.
+func RegisterSecretResolver(name string, resolver SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers[name] = resolver
+}
+
+// fileSecret read a secret file, without trailing new line
+func fileSecret(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// envSecret read a secret from an environment variable
+func envSecret(path string) (string, error) {
+	name := strings.TrimPrefix(path, "/")
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", name)
+}
+
+// resolveSecret resolve a secret reference with the registered resolver
+func resolveSecret(ref string) (string, error) {
+	rest := strings.TrimPrefix(ref, SecretPrefix)
+	name, path := rest, ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		name, path = rest[:i], rest[i:]
+	}
+
+	secretResolversMu.RLock()
+	resolver, ok := secretResolvers[name]
+	secretResolversMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no secret resolver registered for %q", name)
+	}
+	return resolver.ResolveSecret(path)
+}
+
+// resolveSecrets resolve secret references in values of config files and remote,
+// after interpolation. Resolved secrets are merged over files and marked to be redacted.
+// It returns ErrSecretUnresolved listing every secret which can not be resolved.
+func (p *Properties) resolveSecrets() error {
+	refs := map[string]string{}
+	for _, layers := range [][]layer{p.remote, p.layers} {
+		for _, l := range layers {
+			for key, value := range l.values {
+				if v, ok := p.interpolated[key]; ok {
+					value = v
+				}
+				if s, ok := value.(string); ok && strings.HasPrefix(s, SecretPrefix) {
+					refs[key] = s
+				} else {
+					delete(refs, key)
+				}
+			}
+		}
+	}
+
+	var keys []string
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	p.secrets = map[string]bool{}
+	var errs []string
+	for _, key := range keys {
+		p.secrets[key] = true
+		// env and flags still win over file secrets
+		if _, overridden := p.envOrFlagValue(key); overridden {
+			continue
+		}
+		secret, err := resolveSecret(refs[key])
+		if err != nil {
+			// never log the reference value, only the key
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		if p.interpolated == nil {
+			p.interpolated = map[string]interface{}{}
+		}
+		p.interpolated[key] = secret
+		p.Viper.MergeConfigMap(nest(key, secret))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s", ErrSecretUnresolved, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// IsSecret return true if key value was resolved from a secret reference
+// and must be redacted in dumps and logs
+func (p *Properties) IsSecret(key string) bool {
+	p.rlock()
+	defer p.runlock()
+	return p.secrets[strings.ToLower(key)]
+}
@@ -0,0 +1,70 @@
+package properties
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretReferences(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{"db_pass": "s3cr3t\n"})
+	secretFile := filepath.Join(dir, "db_pass")
+	os.Setenv("PROPERTIES_TEST_API_TOKEN", "t0k3n")
+	defer os.Unsetenv("PROPERTIES_TEST_API_TOKEN")
+	RegisterSecretResolver("test", SecretResolverFunc(func(path string) (string, error) {
+		return strings.ToUpper(strings.TrimPrefix(path, "/")), nil
+	}))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.json"), []byte(`{
+  "db": { "user": "cake", "password": "secret://file`+filepath.ToSlash(secretFile)+`" },
+  "api": { "token": "secret://env/PROPERTIES_TEST_API_TOKEN", "key": "secret://test/key" }
+}`), 0644))
+
+	props := New(Config{ConfigPathes: []string{dir}})
+	assert.Equal(t, "s3cr3t", props.GetString("db.password"))
+	assert.Equal(t, "t0k3n", props.GetString("api.token"))
+	assert.Equal(t, "KEY", props.GetString("api.key"))
+	assert.True(t, props.IsSecret("db.password"))
+	assert.False(t, props.IsSecret("db.user"))
+
+	pv, _ := props.Explain("db.password")
+	assert.True(t, pv.Secret)
+	assert.Equal(t, SourceFile, pv.Source)
+	assert.NotContains(t, pv.String(), "s3cr3t")
+	for _, pv := range props.AllProvenance() {
+		assert.NotContains(t, pv.String(), "t0k3n")
+	}
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.json"), []byte(`{
+  "db": { "password": "secret://env/PROPERTIES_TEST_NOT_SET" },
+  "api": { "key": "secret://unknown/key" }
+}`), 0644))
+	_, err := NewE(Config{ConfigPathes: []string{dir}})
+	assert.True(t, errors.Is(err, ErrSecretUnresolved))
+	assert.Contains(t, err.Error(), "db.password")
+	assert.Contains(t, err.Error(), "api.key")
+}
+
+func TestSecretReferencesTryLoadRemote(t *testing.T) {
+	os.Setenv("PROPERTIES_TEST_REMOTE_TOKEN", "t0k3n")
+	defer os.Unsetenv("PROPERTIES_TEST_REMOTE_TOKEN")
+	mem := NewMemorySource()
+	mem.Set("/config/app", map[string]interface{}{"api": map[string]interface{}{"token": "secret://env/PROPERTIES_TEST_REMOTE_TOKEN"}})
+	RegisterSource("memory-secret", mem)
+
+	props := New(Config{ConfigType: "json"})
+	props.Set("remote.name", "memory-secret")
+	props.Set("remote.url", "memory")
+	props.Set("remote.path", "/config/app")
+	assert.NoError(t, props.TryLoadRemotePropertiesE())
+	assert.Equal(t, "t0k3n", props.GetString("api.token"))
+	assert.True(t, props.IsSecret("api.token"))
+
+	mem.Set("/config/app", map[string]interface{}{"api": map[string]interface{}{"token": "secret://env/PROPERTIES_TEST_NOT_SET"}})
+	assert.True(t, errors.Is(props.TryLoadRemotePropertiesE(), ErrSecretUnresolved))
+}
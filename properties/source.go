@@ -0,0 +1,152 @@
+package properties
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// ErrWatchNotSupported is returned by Source.Watch when the source can not push changes,
+// callers should poll Fetch instead
+var ErrWatchNotSupported = errors.New("properties: source does not support watch")
+
+// Source is a remote configuration backend, registered by name with RegisterSource.
+// RemoteProvider.Name selects the source used to read it.
+type Source interface {
+	// Fetch return settings stored at provider Url and Path
+	Fetch(ctx context.Context, provider RemoteProvider) (map[string]interface{}, error)
+
+	// Watch return a channel receiving settings each time they change at provider Url and Path,
+	// closed when ctx is done. It returns ErrWatchNotSupported if the source can not push changes.
+	Watch(ctx context.Context, provider RemoteProvider) (<-chan map[string]interface{}, error)
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]Source{
+		"etcd":   viperSource{},
+		"etcd3":  viperSource{},
+		"consul": viperSource{},
+		"http":   &HTTPSource{},
+		"https":  &HTTPSource{},
+	}
+)
+
+// RegisterSource makes a remote configuration source available by name,
+// to be used as RemoteProvider.Name. "etcd", "etcd3", "consul", "http" and "https"
+// are registered by default and can be replaced.
+func RegisterSource(name string, source Source) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[name] = source
+}
+
+// lookupSource return the source registered for name
+func lookupSource(name string) (Source, error) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	if source, ok := sources[name]; ok {
+		return source, nil
+	}
+	return nil, fmt.Errorf("no source registered for %q", name)
+}
+
+// parseSettings parse a remote value with viper, configType default to json
+func parseSettings(configType string, content []byte) (map[string]interface{}, error) {
+	if configType == "" {
+		configType = DefaultConfigType
+	}
+	v := viper.New()
+	v.SetConfigType(configType)
+	if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+	return v.AllSettings(), nil
+}
+
+// viperSource reads etcd and consul through viper remote support,
+// enabled by the blank import of github.com/spf13/viper/remote.
+type viperSource struct{}
+
+// viperProvider adapts a RemoteProvider to viper.RemoteProvider
+type viperProvider struct {
+	provider RemoteProvider
+}
+
+func (rp viperProvider) Provider() string      { return rp.provider.Name }
+func (rp viperProvider) Endpoint() string      { return rp.provider.Url }
+func (rp viperProvider) Path() string          { return rp.provider.Path }
+func (rp viperProvider) SecretKeyring() string { return rp.provider.KeyFile }
+
+func (viperSource) Fetch(ctx context.Context, provider RemoteProvider) (map[string]interface{}, error) {
+	if viper.RemoteConfig == nil {
+		return nil, errors.New("viper remote support is not enabled")
+	}
+
+	type result struct {
+		settings map[string]interface{}
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reader, err := viper.RemoteConfig.Get(viperProvider{provider})
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		var content bytes.Buffer
+		if _, err = content.ReadFrom(reader); err != nil {
+			done <- result{err: err}
+			return
+		}
+		settings, err := parseSettings(provider.ConfigType, content.Bytes())
+		done <- result{settings, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-done:
+		return r.settings, r.err
+	}
+}
+
+func (viperSource) Watch(ctx context.Context, provider RemoteProvider) (<-chan map[string]interface{}, error) {
+	if viper.RemoteConfig == nil {
+		return nil, errors.New("viper remote support is not enabled")
+	}
+
+	responses, quit := viper.RemoteConfig.WatchChannel(viperProvider{provider})
+	changes := make(chan map[string]interface{})
+	go func() {
+		defer close(changes)
+		defer close(quit)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp, ok := <-responses:
+				if !ok {
+					return
+				}
+				if resp.Error != nil {
+					continue
+				}
+				settings, err := parseSettings(provider.ConfigType, resp.Value)
+				if err != nil {
+					continue
+				}
+				select {
+				case changes <- settings:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
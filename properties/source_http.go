@@ -0,0 +1,48 @@
+package properties
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// HTTPSource reads settings with a GET on provider Url + Path,
+// e.g. RemoteProvider{Name: "https", Url: "https://config.me", Path: "/app.json"}.
+// Body is parsed as provider ConfigType, json by default.
+// Registered as "http" and "https" with http.DefaultClient.
+type HTTPSource struct {
+	// Client used for requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+func (s *HTTPSource) Fetch(ctx context.Context, provider RemoteProvider) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, provider.Url+provider.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var client = s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s%s: %s", provider.Url, provider.Path, resp.Status)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseSettings(provider.ConfigType, content)
+}
+
+// Watch is not supported, HTTP settings are polled
+func (s *HTTPSource) Watch(ctx context.Context, provider RemoteProvider) (<-chan map[string]interface{}, error) {
+	return nil, ErrWatchNotSupported
+}
@@ -0,0 +1,111 @@
+package properties
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemorySource keeps settings in memory by path, provider Url is ignored.
+// Useful to test remote layering without a real cluster:
+//
+//	mem := properties.NewMemorySource()
+//	mem.Set("/config/app", map[string]interface{}{"name": "Cake"})
+//	properties.RegisterSource("memory", mem)
+//	c.Providers = []properties.RemoteProvider{{Name: "memory", Path: "/config/app"}}
+type MemorySource struct {
+	mu       sync.Mutex
+	settings map[string]map[string]interface{}
+	watchers map[string][]chan map[string]interface{}
+}
+
+// NewMemorySource return an empty MemorySource
+func NewMemorySource() *MemorySource {
+	return &MemorySource{
+		settings: map[string]map[string]interface{}{},
+		watchers: map[string][]chan map[string]interface{}{},
+	}
+}
+
+// Set store settings at path and notify watchers of path
+func (s *MemorySource) Set(path string, settings map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings[path] = settings
+	for _, watcher := range s.watchers[path] {
+		// keep only the latest settings for slow watchers
+		select {
+		case <-watcher:
+		default:
+		}
+		watcher <- copySettings(settings)
+	}
+}
+
+// Delete remove settings at path, next Fetch fails
+func (s *MemorySource) Delete(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.settings, path)
+}
+
+func (s *MemorySource) Fetch(ctx context.Context, provider RemoteProvider) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings, ok := s.settings[provider.Path]
+	if !ok {
+		return nil, fmt.Errorf("no settings at %s", provider.Path)
+	}
+	return copySettings(settings), nil
+}
+
+func (s *MemorySource) Watch(ctx context.Context, provider RemoteProvider) (<-chan map[string]interface{}, error) {
+	watcher := make(chan map[string]interface{}, 1)
+	s.mu.Lock()
+	s.watchers[provider.Path] = append(s.watchers[provider.Path], watcher)
+	s.mu.Unlock()
+
+	changes := make(chan map[string]interface{})
+	go func() {
+		defer close(changes)
+		defer s.unwatch(provider.Path, watcher)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case settings := <-watcher:
+				select {
+				case changes <- settings:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// unwatch remove a watcher of path
+func (s *MemorySource) unwatch(path string, watcher chan map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	watchers := s.watchers[path]
+	for i, w := range watchers {
+		if w == watcher {
+			s.watchers[path] = append(watchers[:i], watchers[i+1:]...)
+			return
+		}
+	}
+}
+
+// copySettings deep copy nested settings maps
+func copySettings(settings map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		if sub, ok := value.(map[string]interface{}); ok {
+			value = copySettings(sub)
+		}
+		copied[key] = value
+	}
+	return copied
+}
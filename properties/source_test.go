@@ -0,0 +1,85 @@
+package properties
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySource(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{"app.json": `{"name": "Cake"}`})
+
+	mem := NewMemorySource()
+	mem.Set("/config/app", map[string]interface{}{
+		"Name": "Remote",
+		"db":   map[string]interface{}{"port": 28015},
+	})
+	RegisterSource("memory-test", mem)
+
+	props := New(Config{
+		ConfigPathes: []string{dir},
+		Providers:    []RemoteProvider{{Name: "memory-test", Path: "/config/app"}},
+	})
+	// remote stays under config files
+	assert.Equal(t, "Cake", props.GetString("name"))
+	assert.Equal(t, 28015, props.GetInt("db.port"))
+	pv, _ := props.Explain("db.port")
+	assert.Equal(t, SourceRemote, pv.Source)
+	assert.Equal(t, "/config/app", pv.Path)
+
+	// remote stays over defaults
+	props.SetDefault("db.port", 1)
+	assert.Equal(t, 28015, props.GetInt("db.port"))
+	pv, _ = props.Explain("db.port")
+	assert.Equal(t, SourceRemote, pv.Source)
+	assert.Equal(t, 1, pv.Shadowed[0].Value)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := mem.Watch(ctx, RemoteProvider{Path: "/config/app"})
+	assert.NoError(t, err)
+	mem.Set("/config/app", map[string]interface{}{"name": "Changed"})
+	select {
+	case settings := <-changes:
+		assert.Equal(t, "Changed", settings["name"])
+	case <-time.After(time.Second):
+		t.Fatal("change not received")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/config/app.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"amiauth": {"baseurl": "http://remote.me"}}`)
+	}))
+	defer server.Close()
+
+	props := New(Config{
+		ConfigType: "json",
+		Providers: []RemoteProvider{
+			{Name: "unknown", Url: server.URL, Path: "/config/app.json"},
+			{Name: "http", Url: server.URL, Path: "/notfound.json"},
+			{Name: "http", Url: server.URL, Path: "/config/app.json"},
+		},
+	})
+	assert.Equal(t, "http://remote.me", props.GetString("amiauth.baseurl"))
+
+	_, err := NewE(Config{
+		ConfigType: "json",
+		Providers:  []RemoteProvider{{Name: "http", Url: server.URL, Path: "/notfound.json"}},
+	})
+	assert.True(t, errors.Is(err, ErrRemoteUnavailable))
+	assert.Contains(t, err.Error(), "404")
+
+	_, err = (&HTTPSource{}).Watch(context.Background(), RemoteProvider{})
+	assert.Equal(t, ErrWatchNotSupported, err)
+}
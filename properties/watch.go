@@ -0,0 +1,337 @@
+package properties
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ChangeFunc is called when the merged properties view is rebuilt,
+// with settings before and after the change.
+type ChangeFunc func(old, new map[string]interface{})
+
+// OnChange register a callback fired each time properties change.
+// Callbacks should be registered before calling Watch or WatchRemote.
+func (p *Properties) OnChange(fn ChangeFunc) {
+	p.onChange = append(p.onChange, fn)
+}
+
+// Watch watches base config file and mode config files loaded by LoadModeProperties, with the files they include.
+// On any change the merged view is rebuilt in the same order: base file, then mode files,
+// then env, then flags. Watching stop when ctx is done.
+// Reloads run in a background goroutine, Properties getters wait for a reload to finish,
+// so properties can be read from other goroutines. Use p.Viper directly only when not watching.
+// OnChange callbacks run on the reload goroutine once the view is rebuilt.
+// It returns ErrConfigNotFound if no config file was loaded.
+func (p *Properties) Watch(ctx context.Context) error {
+	var files []string
+	p.rlock()
+	for _, l := range p.layers {
+		// files of Config.FS, e.g. embedded, do not change
+		if l.fsys == nil {
+			files = append(files, filepath.Clean(l.path))
+		}
+	}
+	p.runlock()
+	if len(files) == 0 {
+		return fmt.Errorf("%w: no config file to watch", ErrConfigNotFound)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// watch directories instead of files, editors often replace files on save
+	dirs := map[string]bool{}
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		if err = watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+		dirs[dir] = true
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isWatched(files, event) {
+					continue
+				}
+				if err := p.reload(); err != nil {
+					log.Printf("Error reloading config: %s \n", err)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error watching config: %s \n", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// isWatched return true if event is a content change of one of files
+func isWatched(files []string, event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	for _, file := range files {
+		if name == file {
+			return true
+		}
+	}
+	return false
+}
+
+// reload rebuild the merged view from base and mode config files,
+// then fire change callbacks if settings changed.
+// If a file is invalid, or references or secrets can not be resolved, the current view is kept.
+// Env, flags and overrides are resolved by viper on read, so they keep precedence.
+func (p *Properties) reload() error {
+	return p.update(func(configType string) error {
+		// read and check every file before touching current view
+		layers, err := p.readFiles(p.files, configType)
+		if err != nil {
+			return err
+		}
+
+		previous := p.snapshot()
+		p.layers = layers
+		if err := p.rebuild(configType); err != nil {
+			// keep current view, references are not resolved again
+			p.restore(previous, configType)
+			return err
+		}
+		return nil
+	})
+}
+
+// viewState is the state of the merged view rebuilt on change
+type viewState struct {
+	layers       []layer
+	remote       []layer
+	envLists     []layer
+	interpolated map[string]interface{}
+	secrets      map[string]bool
+}
+
+// snapshot return the current view state, restored if a rebuild fails
+func (p *Properties) snapshot() viewState {
+	s := viewState{
+		layers:       append([]layer{}, p.layers...),
+		remote:       append([]layer{}, p.remote...),
+		envLists:     append([]layer{}, p.envLists...),
+		interpolated: map[string]interface{}{},
+		secrets:      map[string]bool{},
+	}
+	for key, value := range p.interpolated {
+		s.interpolated[key] = value
+	}
+	for key := range p.secrets {
+		s.secrets[key] = true
+	}
+	return s
+}
+
+// rebuild merge config files, then resolve env lists, references and secrets over them
+func (p *Properties) rebuild(configType string) error {
+	if err := p.mergeFiles(configType); err != nil {
+		return err
+	}
+	p.applyEnv()
+	if err := p.interpolate(); err != nil {
+		return err
+	}
+	return p.resolveSecrets()
+}
+
+// restore set back the view of a snapshot, with the values it had resolved
+func (p *Properties) restore(s viewState, configType string) {
+	p.layers, p.remote, p.envLists = s.layers, s.remote, s.envLists
+	p.interpolated, p.secrets = s.interpolated, s.secrets
+
+	// these layers were merged before
+	if err := p.mergeFiles(configType); err != nil {
+		log.Printf("Error restoring config: %s \n", err)
+	}
+	for _, l := range p.envLists {
+		for key, value := range l.values {
+			p.Viper.MergeConfigMap(nest(key, value))
+		}
+	}
+	for key, value := range p.interpolated {
+		p.Viper.MergeConfigMap(nest(key, value))
+	}
+}
+
+// WatchRemote watches remote providers loaded by New or TryLoadRemoteProperties.
+// Sources which can push changes are watched, others are polled every interval.
+// On change the remote layer is replaced, it stays under config files, env and flags,
+// and change callbacks are fired. Watching stop when ctx is done.
+// Like Watch, getters wait for a reload to finish.
+// It returns an error if interval is not positive, ErrRemoteUnavailable if no remote provider was loaded.
+func (p *Properties) WatchRemote(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("properties: WatchRemote interval must be positive, got %s", interval)
+	}
+	p.rlock()
+	providers := append([]RemoteProvider{}, p.providers...)
+	p.runlock()
+	if len(providers) == 0 {
+		return fmt.Errorf("%w: no remote provider to watch", ErrRemoteUnavailable)
+	}
+
+	for i, provider := range providers {
+		source, err := lookupSource(provider.Name)
+		if err != nil {
+			return err
+		}
+		changes, err := source.Watch(ctx, provider)
+		if err == ErrWatchNotSupported {
+			changes = poll(ctx, source, provider, interval)
+		} else if err != nil {
+			return fmt.Errorf("%w: %s %s%s: %v", ErrRemoteUnavailable, provider.Name, provider.Url, provider.Path, err)
+		}
+
+		go func(i int, changes <-chan map[string]interface{}) {
+			for settings := range changes {
+				if err := p.reloadRemote(i, settings); err != nil {
+					log.Printf("Error reloading remote config: %s \n", err)
+				}
+			}
+		}(i, changes)
+	}
+
+	return nil
+}
+
+// poll fetch provider every interval, sending settings when they change
+func poll(ctx context.Context, source Source, provider RemoteProvider, interval time.Duration) <-chan map[string]interface{} {
+	changes := make(chan map[string]interface{})
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last map[string]interface{}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			settings, err := fetchSource(ctx, source, provider)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Error polling remote config %s %s%s: %s \n", provider.Name, provider.Url, provider.Path, err)
+				}
+				continue
+			}
+			if reflect.DeepEqual(settings, last) {
+				continue
+			}
+			last = settings
+
+			select {
+			case changes <- settings:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// reloadRemote replace the remote layer i with settings,
+// then fire change callbacks if settings changed.
+// If references or secrets can not be resolved, the current view is kept.
+func (p *Properties) reloadRemote(i int, settings map[string]interface{}) error {
+	return p.update(func(configType string) error {
+		provider := p.providers[i]
+		settings = normalizeSettings(settings)
+
+		previous := p.snapshot()
+		p.remote[i] = remoteLayer(provider, settings)
+
+		// remote layers are merged under config files, rebuild it
+		if err := p.rebuild(configType); err != nil {
+			p.restore(previous, configType)
+			return err
+		}
+
+		fetchedAt := time.Now()
+		if err := p.Config.writeRemoteCache(provider, settings, fetchedAt); err != nil {
+			log.Printf("Error writing remote config cache: %s \n", err)
+		}
+		p.remoteStatus[i] = RemoteStatus{Provider: provider, FetchedAt: fetchedAt}
+		return nil
+	})
+}
+
+// update run fn with the config type holding the lock, then fire change callbacks if settings changed.
+// Callbacks run once the lock is released, so they can read properties.
+func (p *Properties) update(fn func(configType string) error) error {
+	p.lock()
+	old := p.Viper.AllSettings()
+	err := fn(p.stringOrDefault(ConfigTypeTag, p.Config.ConfigType))
+	current := p.Viper.AllSettings()
+	p.unlock()
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(old, current) {
+		return nil
+	}
+	for _, fn := range p.onChange {
+		fn(old, current)
+	}
+	return nil
+}
+
+// lock serializes rebuilds of the merged view, and changes made by code
+func (p *Properties) lock() {
+	if p.mu != nil {
+		p.mu.Lock()
+	}
+}
+
+func (p *Properties) unlock() {
+	if p.mu != nil {
+		p.mu.Unlock()
+	}
+}
+
+// rlock guards reads against rebuilds of the merged view
+func (p *Properties) rlock() {
+	if p.mu != nil {
+		p.mu.RLock()
+	}
+}
+
+func (p *Properties) runlock() {
+	if p.mu != nil {
+		p.mu.RUnlock()
+	}
+}
@@ -0,0 +1,216 @@
+package properties
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchReloadBaseAndMode(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json":      `{"name": "Cake", "url": "http://base.me"}`,
+		"test.app.json": `{"url": "http://test.me"}`,
+	})
+	mode := filepath.Join(dir, "test.app.json")
+
+	c := NewConfig()
+	c.ConfigPathes = []string{dir}
+	c.DefaultConfigMode = "test"
+	props := New(c)
+	props.LoadModeProperties(true)
+	props.Set("forced", "yes")
+	assert.Equal(t, "http://test.me", props.GetString("url"))
+
+	changes := make(chan [2]map[string]interface{}, 10)
+	props.OnChange(func(old, new map[string]interface{}) {
+		changes <- [2]map[string]interface{}{old, new}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, props.Watch(ctx))
+
+	assert.NoError(t, ioutil.WriteFile(mode, []byte(`{"url": "http://test2.me"}`), 0644))
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, "http://test.me", change[0]["url"])
+		assert.Equal(t, "http://test2.me", change[1]["url"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("change callback not fired")
+	}
+
+	// base layer is kept under mode layer, overrides still win
+	assert.Equal(t, "Cake", props.GetString("name"))
+	assert.Equal(t, "http://test2.me", props.GetString("url"))
+	assert.Equal(t, "yes", props.GetString("forced"))
+}
+
+func TestReadWhileReloading(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{"app.json": `{"count": 0}`})
+	props := New(Config{ConfigPathes: []string{dir}})
+
+	changes := make(chan int, 100)
+	props.OnChange(func(old, new map[string]interface{}) {
+		// callbacks can read properties
+		changes <- props.GetInt("count")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, props.Watch(ctx))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			props.GetInt("count")
+			props.AllSettings()
+			props.Explain("count")
+		}
+	}()
+
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.json"), []byte(fmt.Sprintf(`{"count": %d}`, i)), 0644))
+		select {
+		case count := <-changes:
+			assert.Equal(t, i, count)
+		case <-time.After(5 * time.Second):
+			t.Fatal("change callback not fired")
+		}
+	}
+	cancel()
+	<-done
+}
+
+func TestReloadKeepsViewOnSecretError(t *testing.T) {
+	os.Setenv("PROPERTIES_TEST_RELOAD_PASS", "s3cr3t")
+	defer os.Unsetenv("PROPERTIES_TEST_RELOAD_PASS")
+	dir := writeConfigDir(t, map[string]string{
+		"app.json": `{"name": "Cake", "db": {"host": "localhost", "password": "secret://env/PROPERTIES_TEST_RELOAD_PASS"}}`,
+	})
+	props := New(Config{ConfigPathes: []string{dir}})
+	var fired bool
+	props.OnChange(func(old, new map[string]interface{}) {
+		fired = true
+	})
+
+	os.Unsetenv("PROPERTIES_TEST_RELOAD_PASS")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.json"),
+		[]byte(`{"name": "Pie", "db": {"host": "db.me", "password": "secret://env/PROPERTIES_TEST_RELOAD_PASS"}}`), 0644))
+	assert.True(t, errors.Is(props.reload(), ErrSecretUnresolved))
+
+	assert.False(t, fired)
+	assert.Equal(t, "Cake", props.GetString("name"))
+	assert.Equal(t, "localhost", props.GetString("db.host"))
+	assert.Equal(t, "s3cr3t", props.GetString("db.password"))
+	assert.True(t, props.IsSecret("db.password"))
+	pv, _ := props.Explain("db.host")
+	assert.Equal(t, "localhost", pv.Value)
+}
+
+func TestReloadKeepsViewOnInterpolationError(t *testing.T) {
+	dir := writeConfigDir(t, map[string]string{
+		"app.json": `{"url": "http://base.me", "api": "${url}/api", "name": "Cake"}`,
+	})
+	base := filepath.Join(dir, "app.json")
+	props := New(Config{ConfigPathes: []string{dir}})
+	var changes int
+	props.OnChange(func(old, new map[string]interface{}) {
+		changes++
+	})
+
+	// reference cycle
+	assert.NoError(t, ioutil.WriteFile(base, []byte(`{"url": "${api}", "api": "${url}/api", "name": "Pie"}`), 0644))
+	err := props.reload()
+	assert.True(t, errors.Is(err, ErrInterpolation))
+	assert.Contains(t, err.Error(), "api -> url -> api")
+	assert.Equal(t, 0, changes)
+	assert.Equal(t, "http://base.me", props.GetString("url"))
+	assert.Equal(t, "http://base.me/api", props.GetString("api"))
+	assert.Equal(t, "Cake", props.GetString("name"))
+
+	// next valid edit is applied
+	assert.NoError(t, ioutil.WriteFile(base, []byte(`{"url": "http://new.me", "api": "${url}/api"}`), 0644))
+	assert.NoError(t, props.reload())
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, "http://new.me/api", props.GetString("api"))
+	assert.Nil(t, props.Get("name"))
+}
+
+func TestWatchWithoutFile(t *testing.T) {
+	props := New(Config{ConfigType: "json"})
+	assert.Error(t, props.Watch(context.Background()))
+}
+
+func TestWatchRemote(t *testing.T) {
+	mem := NewMemorySource()
+	mem.Set("/config/app", map[string]interface{}{"name": "Cake", "ppu": 0.55, "size": "big", "home": "remote"})
+	RegisterSource("memory-watch", mem)
+
+	var body atomic.Value
+	body.Store(`{"url": "http://remote.me"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body.Load())
+	}))
+	defer server.Close()
+
+	os.Setenv("PROPERTIES_TEST_WATCH_HOME", "env")
+	defer os.Unsetenv("PROPERTIES_TEST_WATCH_HOME")
+	props := New(Config{
+		ConfigType: "json",
+		EnvPrefix:  "PROPERTIES_TEST_WATCH",
+		Providers:  []RemoteProvider{{Name: "memory-watch", Path: "/config/app"}},
+	})
+	props.Set("remote.name", "http")
+	props.Set("remote.url", server.URL)
+	props.Set("remote.path", "/app.json")
+	assert.NoError(t, props.TryLoadRemotePropertiesE())
+	assert.Equal(t, "env", props.GetString("home"))
+	props.SetDefault("size", "small")
+	assert.Equal(t, "big", props.GetString("size"))
+
+	changes := make(chan map[string]interface{}, 10)
+	props.OnChange(func(old, new map[string]interface{}) {
+		changes <- new
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the http source is polled
+	assert.Error(t, props.WatchRemote(ctx, 0))
+	assert.NoError(t, props.WatchRemote(ctx, 10*time.Millisecond))
+
+	// pushed by source
+	mem.Set("/config/app", map[string]interface{}{"name": "Pie", "home": "remote"})
+	select {
+	case settings := <-changes:
+		assert.Equal(t, "Pie", settings["name"])
+		assert.NotContains(t, settings, "ppu")
+		// default is kept once the remote key is removed
+		assert.Equal(t, "small", settings["size"])
+		// env override is kept
+		assert.Equal(t, "env", settings["home"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("change callback not fired for pushed change")
+	}
+
+	// polled
+	body.Store(`{"url": "http://remote2.me"}`)
+	select {
+	case settings := <-changes:
+		assert.Equal(t, "http://remote2.me", settings["url"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("change callback not fired for polled change")
+	}
+}
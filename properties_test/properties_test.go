@@ -1,9 +1,15 @@
 package propertiestest
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/heirko/go-contrib/properties"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -92,3 +98,328 @@ func TestModeLoadConfigBuggyFile(t *testing.T) {
 	},
 		"mode Config file is corrupted and should throw a panic")
 }
+
+func TestModeLoadConfigErrors(t *testing.T) {
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.DefaultConfigMode = "testNotExistMode"
+	props, err := properties.NewE(c)
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(props.LoadModeE(), properties.ErrConfigNotFound))
+
+	c = properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.ConfigName = "notexistconfigfilename"
+	_, err = properties.NewE(c)
+	assert.True(t, errors.Is(err, properties.ErrConfigNotFound))
+	var notFound viper.ConfigFileNotFoundError
+	assert.True(t, errors.As(err, &notFound))
+	var configErr *properties.ConfigError
+	assert.True(t, errors.As(err, &configErr))
+	assert.Equal(t, "notexistconfigfilename", configErr.Name)
+
+	c = properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.ConfigName = "appbuggy"
+	_, err = properties.NewE(c)
+	assert.True(t, errors.Is(err, properties.ErrConfigInvalid))
+
+	props, err = properties.NewE(properties.Config{ConfigPathes: []string{"./resx"}})
+	assert.NoError(t, err)
+	props.Config.TestModeTag = ""
+	props.Config.DefaultConfigMode = ""
+	assert.Equal(t, properties.ErrModeNotSet, props.LoadModeE())
+}
+
+func TestStackedModesLoadConfig(t *testing.T) {
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.DefaultConfigMode = "test, eu"
+
+	props := properties.New(c)
+	props.LoadModeProperties(true)
+
+	assert.Equal(t, []string{"test", "eu"}, props.Modes())
+	assert.Equal(t, "http://tapp.test.me", props.GetString("app.plateform.baseUrl"))
+	assert.Equal(t, "http://tapp.me", props.GetString("app.plateform.baseurlapp"))
+	assert.Equal(t, "eu", props.GetString("app.plateform.region"))
+	assert.Equal(t, 4, props.GetInt("app.plateform.val.t1"))
+
+	c.DefaultConfigMode = "test,testNotExistMode,eu"
+	assert.Panics(t, func() {
+		properties.New(c).LoadModeProperties(true)
+	}, "Missing overlay should throw a panic")
+
+	props = properties.New(c)
+	assert.NotPanics(t, func() {
+		props.LoadModeProperties(false)
+	}, "Missing overlay should not throw a panic")
+	assert.Equal(t, "http://tapp.test.me", props.GetString("app.plateform.baseUrl"))
+	assert.Equal(t, 4, props.GetInt("app.plateform.val.t1"))
+}
+
+func TestExplainProvenance(t *testing.T) {
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.DefaultConfigMode = "test"
+	c.EnvVars = []string{"HOME"}
+
+	props := properties.New(c)
+	props.LoadModeProperties(true)
+
+	pv, ok := props.Explain("app.plateform.baseUrl")
+	assert.True(t, ok)
+	assert.Equal(t, properties.SourceMode, pv.Source)
+	assert.Equal(t, "test.app.json", filepath.Base(pv.Path))
+	assert.Equal(t, "http://tapp.test.me", pv.Value)
+	assert.Len(t, pv.Shadowed, 1)
+	assert.Equal(t, properties.SourceFile, pv.Shadowed[0].Source)
+	assert.Equal(t, "app.json", filepath.Base(pv.Shadowed[0].Path))
+	assert.Equal(t, "http://tapp.me", pv.Shadowed[0].Value)
+
+	pv, ok = props.Explain("name")
+	assert.True(t, ok)
+	assert.Equal(t, properties.SourceFile, pv.Source)
+	assert.Empty(t, pv.Shadowed)
+
+	pv, ok = props.Explain("HOME")
+	assert.True(t, ok)
+	assert.Equal(t, properties.SourceEnv, pv.Source)
+	assert.Equal(t, "HOME", pv.Path)
+
+	pv, ok = props.Explain(properties.ModeTag)
+	assert.True(t, ok)
+	assert.Equal(t, properties.SourceOverride, pv.Source)
+
+	_, ok = props.Explain("notexist")
+	assert.False(t, ok)
+	// a parent key has no single source
+	_, ok = props.Explain("app.plateform")
+	assert.False(t, ok)
+
+	props.SetDefault("app.plateform.timeout", "30s")
+	props.SetDefault("name", "Pie")
+	pv, ok = props.Explain("app.plateform.timeout")
+	assert.True(t, ok)
+	assert.Equal(t, properties.SourceDefault, pv.Source)
+	pv, _ = props.Explain("name")
+	assert.Equal(t, properties.SourceFile, pv.Source)
+	assert.Equal(t, properties.SourceDefault, pv.Shadowed[0].Source)
+
+	all := props.AllProvenance()
+	assert.NotEmpty(t, all)
+	for i := 1; i < len(all); i++ {
+		assert.True(t, all[i-1].Key < all[i].Key)
+	}
+}
+
+func TestModeLoadConfigSchema(t *testing.T) {
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.DefaultConfigMode = "test,eu"
+	c.Schema = properties.JSONSchema([]byte(`{
+  "properties": {
+    "app": { "properties": { "plateform": { "properties": {
+      "region": { "enum": ["us"] },
+      "val": { "properties": { "t1": { "type": "integer" } } }
+    } } } }
+  }
+}`))
+
+	props, err := properties.NewE(c)
+	assert.NoError(t, err)
+
+	err = props.LoadModeE()
+	var serr *properties.SchemaError
+	assert.True(t, errors.As(err, &serr))
+	assert.Len(t, serr.Violations, 1)
+
+	assert.Panics(t, func() {
+		properties.New(c).LoadModeProperties(false)
+	}, "Schema violation should throw a panic")
+}
+
+func TestBindConfig(t *testing.T) {
+	type BindConfig struct {
+		Name    string
+		BaseUrl string `prop:"app.plateform.baseUrl"`
+		T1      int    `prop:"app.plateform.val.t1"`
+		Db      struct {
+			Name string `prop:"db.name" env:"PROPERTIES_TEST_DB_NAME" default:"primimo"`
+			Port int    `prop:"db.port" flag:"bind-db-port" default:"28015" usage:"Database port"`
+		}
+	}
+
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.DefaultConfigMode = "test"
+
+	var bc BindConfig
+	props, err := properties.Bind(&bc, c)
+	assert.NoError(t, err)
+
+	expected := properties.New(c).LoadModeProperties(true)
+	assert.Equal(t, expected.GetString("name"), bc.Name)
+	assert.Equal(t, expected.GetString("app.plateform.baseurl"), bc.BaseUrl)
+	assert.Equal(t, expected.GetInt("app.plateform.val.t1"), bc.T1)
+	assert.Equal(t, "primimo", bc.Db.Name)
+	assert.Equal(t, 28015, bc.Db.Port)
+	// the flag only sets its property key
+	assert.NotContains(t, props.AllKeys(), "bind-db-port")
+	pv, _ := props.Explain("db.port")
+	assert.Equal(t, properties.SourceDefault, pv.Source)
+	assert.Equal(t, "--bind-db-port", pv.Path)
+
+	os.Setenv("PROPERTIES_TEST_DB_NAME", "fromenv")
+	defer os.Unsetenv("PROPERTIES_TEST_DB_NAME")
+	assert.Equal(t, "fromenv", props.GetString("db.name"))
+	pv, _ = props.Explain("db.name")
+	assert.Equal(t, properties.SourceEnv, pv.Source)
+
+	_, err = properties.Bind(bc, c)
+	assert.Error(t, err)
+}
+
+func TestEnvPrefix(t *testing.T) {
+	os.Setenv("PROPTEST_APP_PLATEFORM_BASEURLAPP", "http://env.me")
+	os.Setenv("PROPTEST_APP_PLATEFORM_VAL_T1", "7")
+	os.Setenv("PROPTEST_APP_PLATEFORM_LOCALES", "en;fr;de")
+	defer os.Unsetenv("PROPTEST_APP_PLATEFORM_BASEURLAPP")
+	defer os.Unsetenv("PROPTEST_APP_PLATEFORM_VAL_T1")
+	defer os.Unsetenv("PROPTEST_APP_PLATEFORM_LOCALES")
+
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.DefaultConfigMode = "test"
+	c.EnvPrefix = "proptest"
+	c.EnvListSeparator = ";"
+
+	props := properties.New(c)
+	props.LoadModeProperties(true)
+
+	assert.Equal(t, "PROPTEST_APP_PLATEFORM_BASEURL", props.EnvName("app.plateform.baseurl"))
+	assert.Equal(t, "http://env.me", props.GetString("app.plateform.baseurlapp"))
+	assert.Equal(t, "http://tapp.test.me", props.GetString("app.plateform.baseurl"))
+	// key only defined in mode file
+	assert.Equal(t, 7, props.GetInt("app.plateform.val.t1"))
+	assert.Equal(t, []string{"en", "fr", "de"}, props.GetStringSlice("app.plateform.locales"))
+
+	pv, _ := props.Explain("app.plateform.val.t1")
+	assert.Equal(t, properties.SourceEnv, pv.Source)
+	assert.Equal(t, "PROPTEST_APP_PLATEFORM_VAL_T1", pv.Path)
+	pv, _ = props.Explain("app.plateform.locales")
+	assert.Equal(t, properties.SourceEnv, pv.Source)
+}
+
+func TestInterpolation(t *testing.T) {
+	os.Setenv("PROPERTIES_TEST_HOME", "/home/cake")
+	defer os.Unsetenv("PROPERTIES_TEST_HOME")
+
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.ConfigName = "interpolation"
+	c.DefaultConfigMode = "test"
+
+	props := properties.New(c)
+	assert.Equal(t, "http://myapp.com/private", props.GetString("amiauth.successurl"))
+
+	props.LoadModeProperties(true)
+	assert.Equal(t, "http://test.myapp.com/private", props.GetString("amiauth.successurl"))
+	assert.Equal(t, 8080, props.GetInt("amiauth.portref"))
+	assert.Equal(t, "/home/cake/data", props.GetString("amiauth.home"))
+	assert.Equal(t, "${amiauth.baseurl}", props.GetString("amiauth.literal"))
+	assert.Equal(t, "${amiauth.notexist}", props.GetString("amiauth.unknown"))
+
+	pv, _ := props.Explain("amiauth.successurl")
+	assert.Equal(t, properties.SourceFile, pv.Source)
+
+	c.DefaultConfigMode = "cycle"
+	err := properties.New(c).LoadModeE()
+	assert.True(t, errors.Is(err, properties.ErrInterpolation))
+	assert.Contains(t, err.Error(), "amiauth.baseurl -> amiauth.successurl -> amiauth.baseurl")
+
+	c.DefaultConfigMode = "test"
+	c.StrictInterpolation = true
+	_, err = properties.NewE(c)
+	assert.True(t, errors.Is(err, properties.ErrInterpolation))
+	assert.Contains(t, err.Error(), "amiauth.notexist")
+}
+
+func TestDiffModes(t *testing.T) {
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+
+	diff, err := properties.DiffModes(c, "test", "eu")
+	assert.NoError(t, err)
+	assert.Equal(t, properties.Diff{
+		{Key: "app.plateform.baseurl", Kind: properties.DiffChanged, Old: "http://tapp.test.me", New: "http://tapp.me"},
+		{Key: "app.plateform.region", Kind: properties.DiffAdded, New: "eu"},
+		{Key: "app.plateform.val.t1", Kind: properties.DiffChanged, Old: float64(3), New: float64(4)},
+	}, diff)
+
+	var text bytes.Buffer
+	assert.NoError(t, diff.WriteText(&text))
+	assert.Equal(t, `~ app.plateform.baseurl: http://tapp.test.me -> http://tapp.me
++ app.plateform.region=eu
+~ app.plateform.val.t1: 3 -> 4
+`, text.String())
+
+	var out bytes.Buffer
+	assert.NoError(t, diff.WriteJSON(&out))
+	var decoded []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Len(t, decoded, 3)
+	assert.Equal(t, "added", decoded[1]["kind"])
+	assert.NotContains(t, decoded[1], "old")
+
+	diff, err = properties.DiffModes(c, "test,eu", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, properties.DiffRemoved, diff[0].Kind)
+
+	_, err = properties.DiffModes(c, "test", "testNotExistMode")
+	assert.True(t, errors.Is(err, properties.ErrConfigNotFound))
+}
+
+func TestRequiredKeys(t *testing.T) {
+	c := properties.NewConfig()
+	c.ConfigPathes = []string{"./resx"}
+	c.DefaultConfigMode = "test"
+	c.RequiredKeys = []string{
+		"app.plateform.baseurl:string",
+		"app.plateform.val.t1:int",
+		"rethinkdb.driver-port:int",
+		"amiauth.batter:list",
+		"name:int",
+		"db.url",
+		"app.plateform.region",
+	}
+
+	props, err := properties.NewE(c)
+	assert.NoError(t, err, "required keys are checked once modes are loaded")
+
+	err = props.LoadModeE()
+	var rerr *properties.RequiredError
+	assert.True(t, errors.As(err, &rerr))
+	assert.True(t, errors.Is(err, properties.ErrRequiredKeys))
+	assert.Equal(t, []string{"db.url", "app.plateform.region"}, rerr.Missing)
+	assert.Equal(t, []string{`name: want int, got "Cake"`}, rerr.Mistyped)
+	assert.Equal(t, []string{"test"}, rerr.Modes)
+	assert.Len(t, rerr.Files, 2)
+	assert.Contains(t, err.Error(), "test.app.json")
+
+	assert.Panics(t, func() {
+		properties.New(c).LoadModeProperties(false)
+	}, "Missing required keys should throw a panic")
+
+	c.DefaultConfigMode = "test,eu"
+	c.RequiredKeys = []string{"app.plateform.region", "app.plateform.val.t1:int"}
+	props = properties.New(c)
+	assert.NoError(t, props.LoadModeE())
+
+	os.Setenv("PROPS_TEST_APP_PLATEFORM_VAL_T1", "four")
+	defer os.Unsetenv("PROPS_TEST_APP_PLATEFORM_VAL_T1")
+	c.EnvPrefix = "PROPS_TEST"
+	props = properties.New(c)
+	assert.True(t, errors.As(props.LoadModeE(), &rerr))
+	assert.Equal(t, []string{`app.plateform.val.t1: want int, got "four"`}, rerr.Mistyped)
+}